@@ -0,0 +1,266 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// String renders the parsed path in the canonical bracket notation, e.g.
+// both $.a['b'] and $['a'].b are rendered as $['a']['b'], so that paths
+// written differently can be compared.
+func (j *Jsonpath) String() string {
+	if j.parser == nil {
+		return ""
+	}
+	return "$" + canonicalSegments(j.AST().(*ListNode).Nodes)
+}
+
+// Equal reports whether both paths have the same parsed expression, compared
+// node by node, so $.a['b'] equals $['a'].b and $.a.first(2) equals $.a[:2].
+// The options of the paths are not compared.
+func (j *Jsonpath) Equal(other *Jsonpath) bool {
+	if j.parser == nil || other.parser == nil {
+		return j.parser == nil && other.parser == nil
+	}
+	return equalNodes(j.AST(), other.AST())
+}
+
+// equalNodes compares two nodes and the nodes they hold, ignoring their
+// positions in the expressions
+func equalNodes(a, b Node) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+	switch a := a.(type) {
+	case *ListNode:
+		return equalNodeLists(a.Nodes, b.(*ListNode).Nodes)
+	case *TextNode:
+		return a.Text == b.(*TextNode).Text
+	case *FieldNode:
+		return a.Value == b.(*FieldNode).Value
+	case *IdentifierNode:
+		return a.Name == b.(*IdentifierNode).Name
+	case *ArrayNode:
+		b := b.(*ArrayNode)
+		if len(a.Params) != len(b.Params) {
+			return false
+		}
+		for i := range a.Params {
+			if !equalParams(a.Params[i], b.Params[i]) {
+				return false
+			}
+		}
+		return true
+	case *ArrayElementNode:
+		return equalParams(a.ParamsEntry, b.(*ArrayElementNode).ParamsEntry)
+	case *FilterNode:
+		b := b.(*FilterNode)
+		return a.Operator == b.Operator && equalNodes(a.Left, b.Left) && equalNodes(a.Right, b.Right)
+	case *ArithmeticNode:
+		b := b.(*ArithmeticNode)
+		return a.Operator == b.Operator && equalNodes(a.Left, b.Left) && equalNodes(a.Right, b.Right)
+	case *IntNode:
+		return a.Value == b.(*IntNode).Value
+	case *FloatNode:
+		return a.Value == b.(*FloatNode).Value
+	case *BoolNode:
+		return a.Value == b.(*BoolNode).Value
+	case *UnionNode:
+		return equalListNodes(a.Nodes, b.(*UnionNode).Nodes)
+	case *FunctionNode:
+		b := b.(*FunctionNode)
+		return a.Name == b.Name && equalListNodes(a.Args, b.Args)
+	case *PseudoFieldNode:
+		return a.Name == b.(*PseudoFieldNode).Name
+	case *KeyRegexNode:
+		return a.Regexp.String() == b.(*KeyRegexNode).Regexp.String()
+	case *RegexNode:
+		return a.Regexp.String() == b.(*RegexNode).Regexp.String()
+	case *GlobFieldNode:
+		return a.Pattern == b.(*GlobFieldNode).Pattern
+	case *LiteralNode:
+		return equalValues(a.Value, b.(*LiteralNode).Value)
+	case *IndexListNode:
+		b := b.(*IndexListNode)
+		if len(a.Indexes) != len(b.Indexes) {
+			return false
+		}
+		for i := range a.Indexes {
+			if a.Indexes[i] != b.Indexes[i] {
+				return false
+			}
+		}
+		return true
+	}
+	// the other nodes, like a wildcard, hold nothing but their type
+	return true
+}
+
+func equalNodeLists(a, b []Node) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equalNodes(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalListNodes(a, b []*ListNode) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equalNodes(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalParams compares the params of slices, a param which is not known has
+// no value
+func equalParams(a, b ParamsEntry) bool {
+	if !a.Known || !b.Known {
+		return a.Known == b.Known
+	}
+	return a.Value == b.Value
+}
+
+func canonicalSegments(nodes []Node) string {
+	sb := strings.Builder{}
+	for _, node := range nodes {
+		sb.WriteString(canonical(node))
+	}
+	return sb.String()
+}
+
+// canonical renders a node in the canonical notation
+func canonical(node Node) string {
+	switch node := node.(type) {
+	case *ListNode:
+		return canonicalSegments(node.Nodes)
+	case *FieldNode:
+		return "['" + escapeKey(node.Value) + "']"
+	case *ArrayElementNode:
+		if !node.Known {
+			return "[]"
+		}
+		return "[" + strconv.Itoa(node.Value) + "]"
+	case *IndexListNode:
+		indexes := make([]string, len(node.Indexes))
+		for i, index := range node.Indexes {
+			indexes[i] = strconv.Itoa(index)
+		}
+		return "[[" + strings.Join(indexes, ",") + "]]"
+	case *ArrayNode:
+		params := make([]string, 0, 3)
+		for i, param := range node.Params {
+			if i == 2 && !param.Known {
+				break
+			}
+			if param.Known {
+				params = append(params, strconv.Itoa(param.Value))
+			} else {
+				params = append(params, "")
+			}
+		}
+		return "[" + strings.Join(params, ":") + "]"
+	case *WildcardNode:
+		return "[*]"
+	case *RecursiveNode:
+		return ".."
+	case *UnionNode:
+		members := make([]string, len(node.Nodes))
+		for i, member := range node.Nodes {
+			m := canonical(member)
+			members[i] = strings.TrimSuffix(strings.TrimPrefix(m, "["), "]")
+		}
+		return "[" + strings.Join(members, ",") + "]"
+	case *FilterNode:
+		return "[?(" + canonicalCondition(node) + ")]"
+	case *KeyRegexNode:
+		return ".~/" + strings.Replace(node.Regexp.String(), "/", `\/`, -1) + "/"
+	case *GlobFieldNode:
+		return "." + node.Pattern
+	case *RegexNode:
+		return "/" + strings.Replace(node.Regexp.String(), "/", `\/`, -1) + "/"
+	case *SecondaryRootNode:
+		return "$$"
+	case *ParentNode:
+		return "^"
+	case *LiteralNode:
+		b, _ := json.Marshal(node.Value)
+		return string(b)
+	case *TextNode:
+		return "'" + escapeKey(node.Text) + "'"
+	case *IntNode:
+		return strconv.Itoa(node.Value)
+	case *FloatNode:
+		return strconv.FormatFloat(node.Value, 'f', -1, 64)
+	case *BoolNode:
+		return strconv.FormatBool(node.Value)
+	case *IdentifierNode:
+		return node.Name
+	case *PseudoFieldNode:
+		return "@" + node.Name
+	case *FunctionNode:
+		args := make([]string, len(node.Args))
+		for i, arg := range node.Args {
+			args[i] = canonicalOperand(arg)
+		}
+		return node.Name + "(" + strings.Join(args, ", ") + ")"
+	case *ArithmeticNode:
+		return canonicalOperand(node.Left) + " " + node.Operator + " " + canonicalOperand(node.Right)
+	}
+	return node.String()
+}
+
+// canonicalCondition renders the condition of a filter, the conditions of ||
+// combined by && are parenthesized
+func canonicalCondition(node *FilterNode) string {
+	switch node.Operator {
+	case "exists":
+		return canonicalOperand(node.Left)
+	case "between":
+		lower, upper := node.Left.Nodes[0].(*FilterNode), node.Right.Nodes[0].(*FilterNode)
+		return canonicalOperand(lower.Left) + " between " + canonicalOperand(lower.Right) + " and " + canonicalOperand(upper.Right)
+	case "&&", "||":
+		conditions := make([]string, 2)
+		for i, operand := range []*ListNode{node.Left, node.Right} {
+			condition := operand.Nodes[0].(*FilterNode)
+			conditions[i] = canonicalCondition(condition)
+			if node.Operator == "&&" && condition.Operator == "||" {
+				conditions[i] = "(" + conditions[i] + ")"
+			}
+		}
+		return conditions[0] + " " + node.Operator + " " + conditions[1]
+	case "all", "any":
+		return node.Operator + " " + canonicalCondition(node.Left.Nodes[0].(*FilterNode))
+	case "!":
+		condition := node.Left.Nodes[0].(*FilterNode)
+		if condition.Operator == "exists" || condition.Operator == "!" {
+			return "!" + canonicalCondition(condition)
+		}
+		return "!(" + canonicalCondition(condition) + ")"
+	}
+	return canonicalOperand(node.Left) + " " + node.Operator + " " + canonicalOperand(node.Right)
+}
+
+// canonicalOperand renders an operand of a filter or a function, which is
+// either a value or a path relative to the current element
+func canonicalOperand(operand *ListNode) string {
+	if len(operand.Nodes) > 0 {
+		switch operand.Nodes[0].Type() {
+		case NodeText, NodeInt, NodeFloat, NodeBool, NodeRegex, NodeFunction, NodePseudoField, NodeArithmetic, NodeLiteral, NodeSecondaryRoot:
+			return canonicalSegments(operand.Nodes)
+		}
+	}
+	return "@" + canonicalSegments(operand.Nodes)
+}
@@ -0,0 +1,100 @@
+package jsonpath
+
+import (
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	cases := []struct {
+		exprs       []string
+		expectation string
+	}{
+		{[]string{`$.a['b']`, `$['a'].b`, `$["a"]["b"]`, `@.a.b`}, `$['a']['b']`},
+		{[]string{`$..book[?(@.price<10)]`, `$..['book'][?(@['price'] < 10)]`}, `$..['book'][?(@['price'] < 10)]`},
+		{[]string{`$[ 0 , 1 ]`, `$[0,1]`}, `$[0,1]`},
+		{[]string{`$.*`, `$[*]`, `$[ * ]`}, `$[*]`},
+		{[]string{`$[1:]`, `$[1::]`}, `$[1:]`},
+		{[]string{`$[::-1]`}, `$[::-1]`},
+		{[]string{`$.a.last(2)`, `$.a[-2:]`}, `$['a'][-2:]`},
+		{[]string{`$.a.first(2)`, `$.a[:2]`}, `$['a'][:2]`},
+		{[]string{`$[[2,0]]`, `$[ [2, 0] ]`}, `$[[2,0]]`},
+		{[]string{`$[?(@.x between 1 and @.y)]`}, `$[?(@['x'] between 1 and @['y'])]`},
+		{[]string{`$[?(@.id==$$.targetId)]`}, `$[?(@['id'] == $$['targetId'])]`},
+		{[]string{`$[?((@.a || @.b) && @.c>1)]`}, `$[?((@['a'] || @['b']) && @['c'] > 1)]`},
+		{[]string{`$['it\'s']`, `$["it's"]`}, `$['it\'s']`},
+		{[]string{`$[?(@.a)]`, `$[?( @.a )]`}, `$[?(@['a'])]`},
+		{[]string{`$[?(@.name == "x")]`, `$[?(@.name=='x')]`}, `$[?(@['name'] == 'x')]`},
+		{[]string{`$[?(@.c == [0, 0])]`, `$[?(@.c==[0,0])]`}, `$[?(@['c'] == [0,0])]`},
+		{[]string{`$[?(length(@.a) > 1.5)]`}, `$[?(length(@['a']) > 1.5)]`},
+		{[]string{`$[?(@index % 2 == 0)]`, `$[?(@index%2==0)]`}, `$[?(@index % 2 == 0)]`},
+		{[]string{`$[?(@.a%2 % 2==1)]`}, `$[?(@['a'] % 2 % 2 == 1)]`},
+		{[]string{`$.~/^a\/b/`}, `$.~/^a\/b/`},
+		{[]string{`$[?(@.a=~/sign in (now)/ && @.b =~ /x || [(]/i)]`}, `$[?(@['a'] =~ /sign in (now)/ && @['b'] =~ /(?i)x || [(]/)]`},
+		{[]string{`$.user_*.id`}, `$.user_*['id']`},
+		{[]string{`$.nth(2, 1)`, `$[1::2]`}, `$[1::2]`},
+		{[]string{`$..price^.title`, `$..['price'] ^ .title`}, `$..['price']^['title']`},
+		{[]string{`$[?(@.a^='x' && @.b $= 'y' || @.c*='z')]`}, `$[?(@['a'] ^= 'x' && @['b'] $= 'y' || @['c'] *= 'z')]`},
+		{[]string{`$[?(all @.s[*]>50 || any @.t[*] == 'x')]`}, `$[?(all @['s'][*] > 50 || any @['t'][*] == 'x')]`},
+		{[]string{`$[?(!(@.a>1 && @.b<2))]`, `$[?( ! ( @.a > 1 && @.b < 2 ) )]`}, `$[?(!(@['a'] > 1 && @['b'] < 2))]`},
+		{[]string{`$[?(!@.a || !!@.b)]`}, `$[?(!@['a'] || !!@['b'])]`},
+		{[]string{`$[?(@ == true)]`}, `$[?(@ == true)]`},
+	}
+	for _, c := range cases {
+		for _, expr := range c.exprs {
+			j, err := New(expr, expr)
+			if err != nil {
+				t.Fatalf("%s: %v", expr, err)
+			}
+			if s := j.String(); s != c.expectation {
+				t.Errorf("%s: rendered as %s, the expectation %s", expr, s, c.expectation)
+			}
+			// the canonical form is parsed to the same path
+			again, err := New(c.expectation, j.String())
+			if err != nil {
+				t.Errorf("%s: cannot parse the canonical form: %v", expr, err)
+			} else if again.String() != c.expectation {
+				t.Errorf("%s: the canonical form is rendered as %s", expr, again.String())
+			}
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	cases := []struct {
+		a, b  string
+		equal bool
+	}{
+		{`$.a['b']`, `$['a'].b`, true},
+		{`$..book[?(@.price<10)]`, `$..['book'][?(@['price'] < 10)]`, true},
+		{`$.a.first(2)`, `$.a[:2]`, true},
+		{`$.nth(2, 1)`, `$[1::2]`, true},
+		{`$[?(@.a && !(@.b == "x"))]`, `$[?( @.a&&!( @.b=='x' ) )]`, true},
+		{`$[?(@.c == [0, {"a": 1}])]`, `$[?(@.c==[0,{"a":1}])]`, true},
+		{`$.~/^a/`, `$.~/^a/`, true},
+		{`$.a.b`, `$.a.c`, false},
+		{`$.a.b`, `$.a.b.c`, false},
+		{`$[0]`, `$[1]`, false},
+		{`$[:2]`, `$[0:2]`, false},
+		{`$[?(@.a > 1)]`, `$[?(@.a >= 1)]`, false},
+		{`$[?(@.a > 1)]`, `$[?(@.a > 1.0)]`, false},
+		{`$[?(@.a && @.b)]`, `$[?(@.a || @.b)]`, false},
+		{`$.*`, `$..*`, false},
+		{`$.~/^a/`, `$.~/^b/`, false},
+	}
+	for _, c := range cases {
+		a, err := New(c.a, c.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := New(c.b, c.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if equal := a.Equal(b); equal != c.equal {
+			t.Errorf("%s and %s: expect equal %v, got %v", c.a, c.b, c.equal, equal)
+		}
+		if equal := b.Equal(a); equal != c.equal {
+			t.Errorf("%s and %s: expect equal %v, got %v", c.b, c.a, c.equal, equal)
+		}
+	}
+}
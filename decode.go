@@ -0,0 +1,144 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// decodeJSON decodes the generic json object like json.Unmarshal, and reports
+// every duplicated key of an object, whose earlier values are overwritten.
+func decodeJSON(data []byte) (interface{}, []string, error) {
+	return decodeReader(bytes.NewReader(data))
+}
+
+// decodeReader is like decodeJSON, but it reads the json from r. r must hold
+// exactly one json value, trailing data is an error, and so is an error of r
+// even if it occurs after the value.
+func decodeReader(r io.Reader) (interface{}, []string, error) {
+	decoder := json.NewDecoder(r)
+	duplicates := make([]string, 0)
+	obj, err := decodeValue(decoder, []interface{}{0}, &duplicates)
+	if err != nil {
+		return nil, nil, err
+	}
+	if decoder.More() {
+		return nil, nil, fmt.Errorf("invalid character after the top-level value")
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		if err == nil {
+			return nil, nil, fmt.Errorf("invalid character after the top-level value")
+		}
+		return nil, nil, err
+	}
+	return obj, duplicates, nil
+}
+
+func decodeValue(decoder *json.Decoder, path []interface{}, duplicates *[]string) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		obj := make(map[string]interface{})
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := token.(string)
+			if _, ok := obj[key]; ok {
+				*duplicates = append(*duplicates, fmt.Sprintf("the key is duplicated, the last value is kept: %s", formatPath(appendPath(path, key))))
+			}
+			if obj[key], err = decodeValue(decoder, appendPath(path, key), duplicates); err != nil {
+				return nil, err
+			}
+		}
+		_, err = decoder.Token() // consume '}'
+		return obj, err
+	case json.Delim('['):
+		arr := make([]interface{}, 0)
+		for decoder.More() {
+			v, err := decodeValue(decoder, appendPath(path, len(arr)), duplicates)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		_, err = decoder.Token() // consume ']'
+		return arr, err
+	}
+	return token, nil
+}
+
+// InitJSON decodes jsonStr as the data to evaluate, a warning is added for
+// every duplicated key since only the last value of the key is kept.
+func (j *Jsonpath) InitJSON(jsonStr string) error {
+	obj, duplicates, err := decodeJSON([]byte(jsonStr))
+	if err != nil {
+		return err
+	}
+	for _, warning := range duplicates {
+		j.AddWarning(warning)
+	}
+	j.InitData(obj)
+	return nil
+}
+
+// NewFromReader is like New, and it decodes the json read from r as the data
+// to evaluate, like InitJSON. r may be any reader, like a gzip.Reader, it must
+// hold exactly one json value.
+func NewFromReader(name string, expr string, r io.Reader) (*Jsonpath, error) {
+	j, err := New(name, expr)
+	if err != nil {
+		return nil, err
+	}
+	obj, duplicates, err := decodeReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode json: %w", err)
+	}
+	for _, warning := range duplicates {
+		j.AddWarning(warning)
+	}
+	j.InitData(obj)
+	return j, nil
+}
+
+// GetElementStreaming returns the element of the json array read from r at
+// index, like $[index], decoding no further than the element. The elements
+// before it are scanned without being decoded, which is much cheaper than
+// decoding the whole array when it is huge. The rest of r is left unread, so
+// it is not validated.
+func GetElementStreaming(r io.Reader, index int) (interface{}, error) {
+	if index < 0 {
+		return nil, fmt.Errorf("cannot stream the element at the negative index %d", index)
+	}
+	decoder := json.NewDecoder(r)
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode json: %w", err)
+	}
+	if token != json.Delim('[') {
+		return nil, fmt.Errorf("cannot stream an element of a non-array value")
+	}
+	for i := 0; decoder.More(); i++ {
+		if i < index {
+			var skipped json.RawMessage
+			if err := decoder.Decode(&skipped); err != nil {
+				return nil, fmt.Errorf("cannot decode json: %w", err)
+			}
+			continue
+		}
+		var element interface{}
+		if err := decoder.Decode(&element); err != nil {
+			return nil, fmt.Errorf("cannot decode json: %w", err)
+		}
+		return element, nil
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("cannot decode json: %w", err)
+	}
+	return nil, fmt.Errorf("index %d out of range for an array of a smaller length", index)
+}
@@ -0,0 +1,257 @@
+package jsonpath
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestInitJSONWarnsOnDuplicateKeys(t *testing.T) {
+	j, err := New("duplicate", `$.key`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.InitJSON(`{"key":1,"key":2,"nested":[{"a":0,"a":[]}]}`); err != nil {
+		t.Fatal(err)
+	}
+	result, err := j.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 || *result[0].(*interface{}) != 2.0 {
+		t.Errorf("expect the last value of the key, got %v", result)
+	}
+	expectation := []string{
+		"the key is duplicated, the last value is kept: $['key']",
+		"the key is duplicated, the last value is kept: $['nested'][0]['a']",
+	}
+	if len(j.warnings) != len(expectation) {
+		t.Fatalf("expect the warnings %v, got %v", expectation, j.warnings)
+	}
+	for i := range expectation {
+		if j.warnings[i] != expectation[i] {
+			t.Errorf("expect the warning %s, got %s", expectation[i], j.warnings[i])
+		}
+	}
+}
+
+func TestInitJSONDecodesLikeUnmarshal(t *testing.T) {
+	sources := []string{bookstoreData, `[]`, `{}`, `"s"`, `[1,null,true,{"a":[[]]}]`}
+	for _, source := range sources {
+		obj, duplicates, err := decodeJSON([]byte(source))
+		if err != nil {
+			t.Errorf("%s: %v", source, err)
+			continue
+		}
+		if len(duplicates) != 0 || !Equal(obj, ConvertToJsonObj(source)) {
+			t.Errorf("%s: decoded to %v", source, obj)
+		}
+	}
+	for _, source := range []string{`{"a":}`, `[1,2`, `{} {}`} {
+		if _, _, err := decodeJSON([]byte(source)); err == nil {
+			t.Errorf("%s: expect an error", source)
+		}
+	}
+}
+
+func TestNewFromReader(t *testing.T) {
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	w.Write([]byte(`{"a": [1, 2, 3]}`))
+	w.Close()
+
+	gz, err := gzip.NewReader(bytes.NewReader(compressed.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := NewFromReader("gzip", `$.a[1]`, gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := j.GetSingle()
+	if err != nil || value != 2.0 {
+		t.Errorf("expect 2, got %v, %v", value, err)
+	}
+
+	// the json is complete, but the gzip trailer is truncated
+	for _, size := range []int{compressed.Len() - 4, compressed.Len() / 2} {
+		gz, err := gzip.NewReader(bytes.NewReader(compressed.Bytes()[:size]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewFromReader("truncated", `$.a`, gz); err == nil {
+			t.Errorf("expect an error for gzipped json truncated to %d bytes", size)
+		}
+	}
+
+	for _, source := range []string{`{"a": [1,`, `{} {}`, `{} x`, `1 ]`} {
+		if _, err := NewFromReader("invalid", `$.a`, bytes.NewReader([]byte(source))); err == nil {
+			t.Errorf("%s: expect an error", source)
+		}
+	}
+}
+
+func TestGetElementStreaming(t *testing.T) {
+	source := `[{"a": 0}, [1, [2]], "3", 4, null]`
+	for index, expectation := range ConvertToJsonObj(source).([]interface{}) {
+		element, err := GetElementStreaming(strings.NewReader(source), index)
+		if err != nil {
+			t.Fatalf("%d: %v", index, err)
+		}
+		if !Equal(element, expectation) {
+			t.Errorf("%d: expect %v, got %v", index, expectation, element)
+		}
+	}
+	// the elements after the one selected are never read
+	if element, err := GetElementStreaming(strings.NewReader(`[1, 2, {"broken`), 1); err != nil || element != 2.0 {
+		t.Errorf("expect 2, got %v, %v", element, err)
+	}
+	for _, c := range []struct {
+		source string
+		index  int
+	}{
+		{source, 5},
+		{source, -1},
+		{`{"a": 1}`, 0},
+		{`[1, 2`, 3},
+		{`[1, }`, 1},
+		{``, 0},
+	} {
+		if element, err := GetElementStreaming(strings.NewReader(c.source), c.index); err == nil {
+			t.Errorf("%s at %d: expect an error, got %v", c.source, c.index, element)
+		}
+	}
+}
+
+// hugeArray is an array of 100000 objects, to compare streaming an element of
+// it with decoding all of it
+var hugeArray = func() []byte {
+	var b bytes.Buffer
+	b.WriteString("[")
+	for i := 0; i < 100000; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `{"id": %d, "name": "item %d", "tags": ["a", "b"]}`, i, i)
+	}
+	b.WriteString("]")
+	return b.Bytes()
+}()
+
+func BenchmarkGetElementStreaming(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := GetElementStreaming(bytes.NewReader(hugeArray), 5); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetElementStreamingLast(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := GetElementStreaming(bytes.NewReader(hugeArray), 99999); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetElementUnmarshal(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		j, err := NewFromReader("unmarshal", `$[5]`, bytes.NewReader(hugeArray))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := j.GetSingle(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestGetRaw(t *testing.T) {
+	source := `
+{
+  "store": {"book": [
+    {"title": "Moby Dick",  "price": 8.990, "meta": {"z": 1, "a": [1.0e2, -0]}},
+    {"title": "Sword of Honour", "price": 12.99}
+  ]}
+}
+`
+	cases := []struct {
+		expr        string
+		expectation []string
+	}{
+		{`$.store.book[0].meta`, []string{`{"z": 1, "a": [1.0e2, -0]}`}},
+		{`$..price`, []string{`8.990`, `12.99`}},
+		{`$.store.book[0].meta.a[*]`, []string{`1.0e2`, `-0`}},
+		{`$.store.book[?(@.price > 10)].title`, []string{`"Sword of Honour"`}},
+		{`$.store.book[0]`, []string{`{"title": "Moby Dick",  "price": 8.990, "meta": {"z": 1, "a": [1.0e2, -0]}}`}},
+		{`$.store.book.length`, []string{`2`}},
+		{`$.missing`, []string{}},
+	}
+	for _, c := range cases {
+		j, err := New(c.expr, c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.SetLengthPseudoField(true)
+		if err := j.InitRawJSON(source); err != nil {
+			t.Fatal(err)
+		}
+		result, err := j.GetRaw()
+		if err != nil {
+			t.Fatalf("%s: %v", c.expr, err)
+		}
+		texts := make([]string, len(result))
+		for i, r := range result {
+			texts[i] = string(r)
+		}
+		if strings.Join(texts, "\n") != strings.Join(c.expectation, "\n") || len(texts) != len(c.expectation) {
+			t.Errorf("%s: expect %q, got %q", c.expr, c.expectation, texts)
+		}
+	}
+
+	j, err := New("root", `$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.InitRawJSON(source); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := j.GetRaw(); err != nil || len(result) != 1 || string(result[0]) != strings.TrimSpace(source) {
+		t.Errorf("expect the whole document, got %q, %v", result, err)
+	}
+	if err := j.InitRawJSON(`{"a": `); err == nil {
+		t.Errorf("expect an error for invalid json")
+	}
+	j, err = New("plain", `$.a`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(ConvertToJsonObj(`{"a": 1}`))
+	if _, err := j.GetRaw(); err == nil {
+		t.Errorf("expect an error without InitRawJSON")
+	}
+
+	if err := j.InitRawJSON(`{"a": 1.0}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Set(2.0); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := j.GetRaw(); err == nil {
+		t.Errorf("expect an error once the data is changed, got %q", result)
+	}
+
+	j, err = New("secondary", `$$.a`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.InitRawJSON(`{"a": 1.0}`); err != nil {
+		t.Fatal(err)
+	}
+	j.InitSecondaryData(ConvertToJsonObj(`{"a": 2}`))
+	if result, err := j.GetRaw(); err == nil {
+		t.Errorf("expect an error for $$, got %q", result)
+	}
+}
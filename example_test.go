@@ -0,0 +1,43 @@
+package jsonpath_test
+
+import (
+	"fmt"
+
+	"github.com/zucong/jsonpath"
+)
+
+func countFilters(node jsonpath.Node) int {
+	count := 0
+	switch node := node.(type) {
+	case *jsonpath.ListNode:
+		for _, n := range node.Nodes {
+			count += countFilters(n)
+		}
+	case *jsonpath.UnionNode:
+		for _, n := range node.Nodes {
+			count += countFilters(n)
+		}
+	case *jsonpath.FilterNode:
+		count = 1 + countFilters(node.Left) + countFilters(node.Right)
+	}
+	return count
+}
+
+func ExampleJsonpath_AST() {
+	j, err := jsonpath.New("example", `$.store.book[?(@.price < 10)].tags[?(@ == "classic")]`)
+	if err != nil {
+		panic(err)
+	}
+	ast := j.AST()
+	for _, node := range ast.(*jsonpath.ListNode).Nodes {
+		fmt.Println(node.Type())
+	}
+	fmt.Println("filters:", countFilters(ast))
+	// Output:
+	// NodeField
+	// NodeField
+	// NodeFilter
+	// NodeField
+	// NodeFilter
+	// filters: 2
+}
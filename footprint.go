@@ -3,6 +3,7 @@ package jsonpath
 import (
 	"errors"
 	"fmt"
+	"sort"
 )
 
 type Footprint interface {
@@ -12,6 +13,10 @@ type Footprint interface {
 	UpdateOne(data interface{}, keyOrIndex interface{}) error
 	UpdateAll(data interface{}) error
 	SelectAll() (Footprint, error)
+	HolderPath() []interface{}
+	WithHolderPath(path []interface{}) Footprint
+	Parent() Footprint
+	WithParent(parent Footprint) Footprint
 	IsVirtual() bool
 	EnforceArraySelection(size int) error
 	EnforceObjectSelection() error
@@ -32,6 +37,8 @@ type MapFootprint struct {
 	Ref           *interface{}
 	SelectionKeys []SelectionKey
 	Virtual       bool
+	Path          []interface{} // keys and indexes leading from the data holder to Ref
+	parent        Footprint     // the footprint of the value holding Ref, see Parent
 }
 
 func NewFootprint(ptr *interface{}, virtualInfo interface{}) Footprint {
@@ -82,9 +89,11 @@ func (mfp MapFootprint) Expand() ([]Footprint, error) {
 	}
 	result := make([]Footprint, 0)
 	ref := (*mfp.Ref).(map[string]interface{})
+	holder := mfp
+	holder.SelectionKeys = nil
 	for _, sk := range mfp.SelectionKeys {
 		v := ref[sk.Key]
-		result = append(result, NewFootprint(&v, sk))
+		result = append(result, newChildFootprint(&v, sk).WithHolderPath(appendPath(mfp.Path, sk.Key)).WithParent(holder))
 	}
 	return result, nil
 }
@@ -93,6 +102,24 @@ func (mfp MapFootprint) HolderPtr() *interface{} {
 	return mfp.Ref
 }
 
+func (mfp MapFootprint) HolderPath() []interface{} {
+	return mfp.Path
+}
+
+func (mfp MapFootprint) WithHolderPath(path []interface{}) Footprint {
+	mfp.Path = path
+	return mfp
+}
+
+func (mfp MapFootprint) Parent() Footprint {
+	return mfp.parent
+}
+
+func (mfp MapFootprint) WithParent(parent Footprint) Footprint {
+	mfp.parent = parent
+	return mfp
+}
+
 func (mfp MapFootprint) UpdateAll(data interface{}) error {
 	ref := (*mfp.Ref).(map[string]interface{})
 	for _, sk := range mfp.SelectionKeys {
@@ -112,8 +139,15 @@ func (mfp MapFootprint) UpdateOne(data interface{}, keyOrIndex interface{}) erro
 
 func (mfp MapFootprint) SelectAll() (Footprint, error) {
 	ref := (*mfp.Ref).(map[string]interface{})
-	sks := make([]SelectionKey, 0)
+	// the keys are sorted, so that the members are selected in the same order
+	// every time, like by GetPage
+	keys := make([]string, 0, len(ref))
 	for key := range ref {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	sks := make([]SelectionKey, 0, len(keys))
+	for _, key := range keys {
 		sks = append(sks, SelectionKey{
 			Key: key,
 			VirtualInfo: VirtualInfo{
@@ -164,7 +198,7 @@ func (mfp MapFootprint) EnforceObjectSelection() error {
 			if s.Virtual {
 				ref[s.Key] = make(map[string]interface{}, 0)
 			} else {
-				return fmt.Errorf("the selection is not an array or a virtual")
+				return fmt.Errorf("cannot select a field of the non-object value at %s", formatPath(appendPath(mfp.Path, s.Key)))
 			}
 		}
 	}
@@ -184,6 +218,8 @@ type ArrayFootprint struct {
 	leaveItAsItIs    bool
 	Ref              *interface{}
 	SelectionIndexes []SelectionIndex
+	Path             []interface{} // keys and indexes leading from the data holder to Ref
+	parent           Footprint     // the footprint of the value holding Ref, see Parent
 	VirtualInfo
 }
 
@@ -202,10 +238,12 @@ func (afp ArrayFootprint) Expand() ([]Footprint, error) {
 	}
 	result := make([]Footprint, 0)
 	ref := (*afp.Ref).([]interface{})
+	holder := afp
+	holder.SelectionIndexes = nil
 	for _, s := range afp.SelectionIndexes {
 		v := ref[s.Index]
 
-		result = append(result, NewFootprint(&v, s))
+		result = append(result, newChildFootprint(&v, s).WithHolderPath(appendPath(afp.Path, s.Index)).WithParent(holder))
 	}
 	return result, nil
 }
@@ -214,6 +252,24 @@ func (afp ArrayFootprint) HolderPtr() *interface{} {
 	return afp.Ref
 }
 
+func (afp ArrayFootprint) HolderPath() []interface{} {
+	return afp.Path
+}
+
+func (afp ArrayFootprint) WithHolderPath(path []interface{}) Footprint {
+	afp.Path = path
+	return afp
+}
+
+func (afp ArrayFootprint) Parent() Footprint {
+	return afp.parent
+}
+
+func (afp ArrayFootprint) WithParent(parent Footprint) Footprint {
+	afp.parent = parent
+	return afp
+}
+
 func (afp ArrayFootprint) UpdateAll(data interface{}) error {
 	ref := (*afp.Ref).([]interface{})
 	for _, si := range afp.SelectionIndexes {
@@ -289,7 +345,7 @@ func (afp ArrayFootprint) EnforceObjectSelection() error {
 			if s.Virtual {
 				ref[s.Index] = make(map[string]interface{}, 0)
 			} else {
-				return fmt.Errorf("the selection is not an array or a virtual")
+				return fmt.Errorf("cannot select a field of the non-object value at %s", formatPath(appendPath(afp.Path, s.Index)))
 			}
 		}
 	}
@@ -299,6 +355,8 @@ func (afp ArrayFootprint) EnforceObjectSelection() error {
 type NonRefFootprint struct {
 	leaveItAsItIs bool
 	value         interface{}
+	path          []interface{}
+	parent        Footprint
 }
 
 func (nfp NonRefFootprint) LeaveItAsItIs() Footprint {
@@ -306,9 +364,12 @@ func (nfp NonRefFootprint) LeaveItAsItIs() Footprint {
 	return nfp
 }
 
+// Expand fails since a scalar has no children, unless the footprint is left
+// as it is, like a value recorded by a recursive descent, then it expands to
+// itself once.
 func (nfp NonRefFootprint) Expand() ([]Footprint, error) {
-	nfp.leaveItAsItIs = false
 	if nfp.leaveItAsItIs {
+		nfp.leaveItAsItIs = false
 		return []Footprint{nfp}, nil
 	}
 	return nil, errors.New("non-reference foot print cannot be expand")
@@ -318,6 +379,24 @@ func (nfp NonRefFootprint) HolderPtr() *interface{} {
 	return &nfp.value
 }
 
+func (nfp NonRefFootprint) HolderPath() []interface{} {
+	return nfp.path
+}
+
+func (nfp NonRefFootprint) WithHolderPath(path []interface{}) Footprint {
+	nfp.path = path
+	return nfp
+}
+
+func (nfp NonRefFootprint) Parent() Footprint {
+	return nfp.parent
+}
+
+func (nfp NonRefFootprint) WithParent(parent Footprint) Footprint {
+	nfp.parent = parent
+	return nfp
+}
+
 func (nfp NonRefFootprint) UpdateAll(data interface{}) error {
 	return errors.New("UpdateAll is not supported by NonRefFootprint")
 }
@@ -341,3 +420,11 @@ func (nfp NonRefFootprint) EnforceArraySelection(size int) error {
 func (nfp NonRefFootprint) EnforceObjectSelection() error {
 	return fmt.Errorf("EnforceObjectSelection is not supported by NonRefFootprint")
 }
+
+// appendPath returns a new path with keyOrIndex appended, so that sibling
+// footprints never share the same backing array.
+func appendPath(path []interface{}, keyOrIndex interface{}) []interface{} {
+	result := make([]interface{}, len(path), len(path)+1)
+	copy(result, path)
+	return append(result, keyOrIndex)
+}
@@ -0,0 +1,128 @@
+package jsonpath
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// function computes the values selected by a function call, every argument is
+// given as the values selected by its expression
+type function func(args [][]interface{}) ([]interface{}, error)
+
+// Func is a function registered by RegisterFunc. Every argument is the value
+// its expression selects, nil when it selects nothing and a []interface{} of
+// the values when it selects several of them. A literal argument is its value.
+// A nil result selects nothing.
+type Func func(args ...interface{}) (interface{}, error)
+
+var functions = map[string]function{
+	"length": length,
+	"count":  count,
+}
+
+// length selects the number of elements of an array, the number of members of
+// an object or the number of characters of a string
+func length(args [][]interface{}) ([]interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("length expects 1 argument, got %d", len(args))
+	}
+	switch len(args[0]) {
+	case 0:
+		return nil, nil
+	case 1:
+	default:
+		return nil, fmt.Errorf("length can only measure one element at a time")
+	}
+	switch v := args[0][0].(type) {
+	case []interface{}:
+		return []interface{}{len(v)}, nil
+	case map[string]interface{}:
+		return []interface{}{len(v)}, nil
+	case string:
+		return []interface{}{utf8.RuneCountInString(v)}, nil
+	}
+	return nil, nil
+}
+
+// count selects the number of values its argument selects, like the number of
+// descendants @..item finds
+func count(args [][]interface{}) ([]interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("count expects 1 argument, got %d", len(args))
+	}
+	return []interface{}{len(args[0])}, nil
+}
+
+func (j *Jsonpath) evalFunction(footprints []Footprint, node *FunctionNode) ([]Footprint, error) {
+	fn, ok := j.lookupFunc(node.Name)
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", node.Name)
+	}
+	footprints = expandFootprints(footprints, true) // a scalar element of a filter is an argument as well
+	result := make([]Footprint, 0)
+	for _, footprint := range footprints {
+		args := make([][]interface{}, len(node.Args))
+		for i, arg := range node.Args {
+			values, err := j.selectValues(footprint, arg)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = values
+		}
+		values, err := fn(args)
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			v := value
+			result = append(result, NewFootprint(&v, nil))
+		}
+	}
+	return result, nil
+}
+
+// RegisterFunc makes fn callable by name in the filters of the path, like
+// $[?(upper(@.name) == "BOB")]. It takes precedence over a built-in function
+// of the same name. An error returned by fn fails the evaluation.
+func (j *Jsonpath) RegisterFunc(name string, fn func(args ...interface{}) (interface{}, error)) {
+	// the functions are shared with the clones, so they are copied on write
+	funcs := make(map[string]function, len(j.funcs)+1)
+	for k, v := range j.funcs {
+		funcs[k] = v
+	}
+	funcs[name] = adaptFunc(name, fn)
+	j.funcs = funcs
+}
+
+// adaptFunc adapts a registered function to the selections its arguments make
+func adaptFunc(name string, fn Func) function {
+	return func(args [][]interface{}) ([]interface{}, error) {
+		values := make([]interface{}, len(args))
+		for i, arg := range args {
+			switch len(arg) {
+			case 0:
+			case 1:
+				values[i] = arg[0]
+			default:
+				values[i] = arg
+			}
+		}
+		value, err := fn(values...)
+		if err != nil {
+			return nil, fmt.Errorf("function %s: %w", name, err)
+		}
+		if value == nil {
+			return nil, nil
+		}
+		return []interface{}{value}, nil
+	}
+}
+
+// lookupFunc finds a registered function or else a built-in one
+func (j *Jsonpath) lookupFunc(name string) (function, bool) {
+	if fn, ok := j.funcs[name]; ok {
+		return fn, true
+	}
+	fn, ok := functions[name]
+	return fn, ok
+}
@@ -1 +1,3 @@
 module github.com/zucong/jsonpath
+
+require gopkg.in/yaml.v3 v3.0.1
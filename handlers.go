@@ -1,9 +1,17 @@
 package jsonpath
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/zucong/jsonpath/template"
 	"log"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func expandFootprints(footprints []Footprint, remainUnexpandableFootprint bool) []Footprint {
@@ -22,6 +30,27 @@ func expandFootprints(footprints []Footprint, remainUnexpandableFootprint bool)
 	return result
 }
 
+// detach replaces the object or the array a footprint holds with a shallow
+// copy when the path is resolved by SetPreview. Resolving a path for Set adds
+// members to objects and grows arrays, and a footprint holds a value through
+// a pointer of its own, so the copy is changed instead of the data.
+func (j *Jsonpath) detach(footprint Footprint) {
+	if !j.dryRun {
+		return
+	}
+	ptr := footprint.HolderPtr()
+	switch v := (*ptr).(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[key] = value
+		}
+		*ptr = m
+	case []interface{}:
+		*ptr = append([]interface{}(nil), v...)
+	}
+}
+
 func (j *Jsonpath) evalList(footprints []Footprint, node *ListNode) ([]Footprint, error) {
 	var err error
 
@@ -46,15 +75,45 @@ func (j *Jsonpath) evalField(footprints []Footprint, node *FieldNode) ([]Footpri
 	footprints = expandFootprints(footprints, false)
 	result := make([]Footprint, 0)
 	for _, fp := range footprints {
+		if j.writeMode {
+			j.detach(fp) // a missing member is added to the object below
+		}
 		ref := fp.HolderPtr()
-		if m, ok := (*ref).(map[string]interface{}); ok {
-			if _, ok := m[node.Value]; ok {
-				result = append(result, MapFootprint{
+		if _, ok := (*ref).(map[string]interface{}); j.lenField && !j.writeMode && node.Value == "length" && !ok {
+			// .length of an object is still its member named length
+			if n, _ := length([][]interface{}{{*ref}}); len(n) == 1 {
+				result = append(result, NewFootprint(&n[0], nil))
+				continue
+			}
+		}
+		if arr, ok := (*ref).([]interface{}); ok && j.polyIndex && !j.writeMode {
+			if i, ok := keyIndex(node.Value, len(arr)); ok {
+				result = append(result, ArrayFootprint{
 					Ref: ref,
-					SelectionKeys: []SelectionKey{{node.Value, VirtualInfo{
+					SelectionIndexes: []SelectionIndex{{
+						Index:       i,
+						VirtualInfo: VirtualInfo{Virtual: false, RealSize: -1},
+					}},
+					Path:   fp.HolderPath(),
+					parent: fp.Parent(),
+				})
+			}
+			continue
+		}
+		if m, ok := (*ref).(map[string]interface{}); ok {
+			if keys := j.fieldKeys(m, node.Value); len(keys) > 0 {
+				sks := make([]SelectionKey, len(keys))
+				for i, key := range keys {
+					sks[i] = SelectionKey{key, VirtualInfo{
 						Virtual:  false,
 						RealSize: -1,
-					}}},
+					}}
+				}
+				result = append(result, MapFootprint{
+					Ref:           ref,
+					SelectionKeys: sks,
+					Path:          fp.HolderPath(),
+					parent:        fp.Parent(),
 				})
 			} else if j.writeMode {
 				(*ref).(map[string]interface{})[node.Value] = make(map[string]interface{})
@@ -64,10 +123,19 @@ func (j *Jsonpath) evalField(footprints []Footprint, node *FieldNode) ([]Footpri
 						Virtual:  true,
 						RealSize: -1,
 					}}},
+					Path:   fp.HolderPath(),
+					parent: fp.Parent(),
 				})
 			} else {
 				j.AddWarning(fmt.Sprintf("cannot find the field: %s", node.Value))
 			}
+		} else if sfp, ok := fp.(StructFootprint); ok {
+			if _, ok := structField(structValue(sfp.Ref), node.Value); ok {
+				sfp.SelectionKeys = []string{node.Value}
+				result = append(result, sfp)
+			} else {
+				j.AddWarning(fmt.Sprintf("cannot find the field: %s", node.Value))
+			}
 		}
 		//} else {
 		//	return nil, fmt.Errorf("cannot use a key string to find a element in a non-map object")
@@ -76,6 +144,41 @@ func (j *Jsonpath) evalField(footprints []Footprint, node *FieldNode) ([]Footpri
 	return result, nil
 }
 
+// keyIndex returns the index of an array of length size a key like "1" or "-1"
+// refers to, ok is false unless the key is an integer written as strconv.Itoa
+// does and it is in range
+func keyIndex(key string, size int) (int, bool) {
+	i, err := strconv.Atoi(key)
+	if err != nil || strconv.Itoa(i) != key {
+		return 0, false
+	}
+	if i < 0 {
+		i += size
+	}
+	return i, i >= 0 && i < size
+}
+
+// fieldKeys returns the keys of m a field name selects, which is the name
+// itself, or with SetTrimKeys every key equal to it once both are trimmed of
+// spaces, in sorted order
+func (j *Jsonpath) fieldKeys(m map[string]interface{}, name string) []string {
+	if !j.trimKeys {
+		if _, ok := m[name]; ok {
+			return []string{name}
+		}
+		return nil
+	}
+	name = strings.TrimSpace(name)
+	keys := make([]string, 0)
+	for key := range m {
+		if strings.TrimSpace(key) == name {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func (j *Jsonpath) inferArrayNode(arrPtr *[]interface{}, node *ArrayNode) (base, limit, step int, needInvert bool) {
 	arr := *arrPtr
 	if len(node.Params) == 1 {
@@ -135,22 +238,39 @@ func (j *Jsonpath) inferArrayNode(arrPtr *[]interface{}, node *ArrayNode) (base,
 	return
 }
 
+// sliceSize returns the size an array grows to when a slice is set, or -1 if
+// the slice never grows an array. [n:m] grows the array to m elements. [n:]
+// grows it to n+1 elements, so that the slice sets at least the element n,
+// and then it sets every element from n to the end. A negative bound is
+// resolved against the length of the array, so it never grows the array,
+// neither does a slice with a negative step unless it starts at a known index.
+func sliceSize(params []ParamsEntry) int {
+	start, end, step := params[0], params[1], params[2]
+	switch {
+	case !start.Known && !end.Known && (!step.Known || step.Value > 0):
+		return -1 // [:] selects every element
+	case start.Value < 0 || end.Known && end.Value < 0:
+		return 0
+	case step.Known && step.Value < 0:
+		if !start.Known {
+			return -1
+		}
+		return start.Value + 1
+	case end.Known:
+		return end.Value
+	default:
+		return start.Value + 1
+	}
+}
+
 func (j *Jsonpath) evalArray(footprints []Footprint, node *ArrayNode) ([]Footprint, error) {
 	if j.writeMode {
+		size := sliceSize(node.Params)
 		for _, footprint := range footprints {
-			tail := 0
-			if !node.Params[0].Known {
-				node.Params[0].Value = 0
-			}
-			if !node.Params[1].Known {
-				tail = node.Params[0].Value + 1
-			} else {
-				tail = node.Params[1].Value
-			}
-			if node.Params[0].Value == 0 && node.Params[1].Value == 0 && node.Params[2].Value == 0 { // wildcard
-				tail = -1
+			if err := j.checkGrow(footprint, size); err != nil {
+				return nil, err
 			}
-			err := footprint.EnforceArraySelection(tail)
+			err := footprint.EnforceArraySelection(size)
 			if err != nil {
 				return nil, err
 			}
@@ -189,6 +309,8 @@ func (j *Jsonpath) evalArray(footprints []Footprint, node *ArrayNode) ([]Footpri
 				ArrayFootprint{
 					Ref:              footprint.HolderPtr(),
 					SelectionIndexes: indexes,
+					Path:             footprint.HolderPath(),
+					parent:           footprint.Parent(),
 				},
 			)
 		} else {
@@ -200,13 +322,20 @@ func (j *Jsonpath) evalArray(footprints []Footprint, node *ArrayNode) ([]Footpri
 
 func (j *Jsonpath) evalArrayElement(footprints []Footprint, node *ArrayElementNode) ([]Footprint, error) {
 	if j.writeMode {
-		if node.Value < 0 {
-			return nil, fmt.Errorf("cannot use a negative index in set mode")
-		} else if !node.Known {
+		if !node.Known {
 			return nil, fmt.Errorf("index unknown in set mode")
 		}
+		// a negative index is resolved against the length of each array
+		// below, it never grows the array
+		size := node.Value + 1
+		if node.Value < 0 {
+			size = 0
+		}
 		for _, footprint := range footprints {
-			err := footprint.EnforceArraySelection(node.Value + 1)
+			if err := j.checkGrow(footprint, size); err != nil {
+				return nil, err
+			}
+			err := footprint.EnforceArraySelection(size)
 			if err != nil {
 				return nil, err
 			}
@@ -226,6 +355,12 @@ func (j *Jsonpath) evalArrayElement(footprints []Footprint, node *ArrayElementNo
 				i = node.Value + len(arr)
 			}
 
+			if j.writeMode && i < 0 {
+				return nil, fmt.Errorf("cannot set the index %d of an array of length %d, a negative index cannot grow an array", node.Value, len(arr))
+			}
+			if j.strict && (i < 0 || i >= len(arr)) {
+				return nil, fmt.Errorf("index %d out of range for array of length %d", node.Value, len(arr))
+			}
 			if i >= 0 && i < len(arr) {
 				indexes = append(indexes, SelectionIndex{
 					Index: i,
@@ -240,8 +375,25 @@ func (j *Jsonpath) evalArrayElement(footprints []Footprint, node *ArrayElementNo
 				ArrayFootprint{
 					Ref:              footprint.HolderPtr(),
 					SelectionIndexes: indexes,
+					Path:             footprint.HolderPath(),
+					parent:           footprint.Parent(),
 				},
 			)
+		} else if m, ok := (*ptr).(map[string]interface{}); ok && (j.indexKeys || j.polyIndex) && !j.writeMode {
+			// the key of an object is never an out of range index, so there
+			// is nothing to warn about when it is missing
+			key := strconv.Itoa(node.Value)
+			if _, ok := m[key]; ok {
+				result = append(result, MapFootprint{
+					Ref: ptr,
+					SelectionKeys: []SelectionKey{{key, VirtualInfo{
+						Virtual:  false,
+						RealSize: -1,
+					}}},
+					Path:   footprint.HolderPath(),
+					parent: footprint.Parent(),
+				})
+			}
 		} else {
 			j.AddWarning("cannot use a index number to find a element in a non-array object")
 		}
@@ -249,17 +401,180 @@ func (j *Jsonpath) evalArrayElement(footprints []Footprint, node *ArrayElementNo
 	return result, nil
 }
 
+func (j *Jsonpath) evalIndexList(footprints []Footprint, node *IndexListNode) ([]Footprint, error) {
+	if j.writeMode {
+		// like a single index, a negative index never grows the array
+		size := 0
+		for _, index := range node.Indexes {
+			if index+1 > size {
+				size = index + 1
+			}
+		}
+		for _, footprint := range footprints {
+			if err := j.checkGrow(footprint, size); err != nil {
+				return nil, err
+			}
+			err := footprint.EnforceArraySelection(size)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	footprints = expandFootprints(footprints, false)
+	result := make([]Footprint, 0)
+	for _, footprint := range footprints {
+		arr, ok := (*footprint.HolderPtr()).([]interface{})
+		if !ok {
+			j.AddWarning("cannot use a index number to find a element in a non-array object")
+			continue
+		}
+		realSize := footprint.(ArrayFootprint).RealSize
+		indexes := make([]SelectionIndex, 0, len(node.Indexes))
+		for _, index := range node.Indexes {
+			i := index
+			if i < 0 {
+				i += len(arr)
+			}
+			if i < 0 || i >= len(arr) {
+				if j.writeMode {
+					return nil, fmt.Errorf("cannot set the index %d of an array of length %d, a negative index cannot grow an array", index, len(arr))
+				}
+				continue
+			}
+			indexes = append(indexes, SelectionIndex{
+				Index: i,
+				VirtualInfo: VirtualInfo{
+					Virtual:  j.writeMode && i >= realSize,
+					RealSize: -1,
+				},
+			})
+		}
+		result = append(result, ArrayFootprint{
+			Ref:              footprint.HolderPtr(),
+			SelectionIndexes: indexes,
+			Path:             footprint.HolderPath(),
+			parent:           footprint.Parent(),
+		})
+	}
+	return result, nil
+}
+
+// checkGrow reports an error when setting the arrays selected by the
+// footprint would grow one of them by more elements than SetMaxGrow allows. A
+// value which is not an array yet grows from no elements.
+func (j *Jsonpath) checkGrow(footprint Footprint, size int) error {
+	if j.maxGrow <= 0 || size < 0 {
+		return nil
+	}
+	for _, path := range selectedPaths(footprint) {
+		length := 0
+		switch ref := (*footprint.HolderPtr()).(type) {
+		case map[string]interface{}:
+			if arr, ok := ref[path[len(path)-1].(string)].([]interface{}); ok {
+				length = len(arr)
+			}
+		case []interface{}:
+			if arr, ok := ref[path[len(path)-1].(int)].([]interface{}); ok {
+				length = len(arr)
+			}
+		}
+		if size-length > j.maxGrow {
+			return fmt.Errorf("cannot grow the array at %s by %d elements, the limit is %d", formatPath(path), size-length, j.maxGrow)
+		}
+	}
+	return nil
+}
+
 func (j *Jsonpath) evalWildcard(footprints []Footprint, node *WildcardNode) ([]Footprint, error) {
 	footprints = expandFootprints(footprints, false)
-	for i, footprint := range footprints {
+	count := 0
+	result := make([]Footprint, 0, len(footprints))
+	for _, footprint := range footprints {
+		if err := j.checkContext(); err != nil {
+			return nil, err
+		}
 		selected, err := footprint.SelectAll()
 		if err != nil {
+			// a scalar has no children, so the wildcard selects nothing of it
 			log.Println("wildcard is only supported by map and array")
 		} else {
-			footprints[i] = selected
+			result = append(result, selected)
+			count += len(selectedPaths(selected))
+			if err := j.checkMaxResults(count); err != nil {
+				return nil, err
+			}
 		}
 	}
-	return footprints, nil
+	return result, nil
+}
+
+func (j *Jsonpath) evalKeyRegex(footprints []Footprint, node *KeyRegexNode) ([]Footprint, error) {
+	footprints = expandFootprints(footprints, false)
+	result := make([]Footprint, 0)
+	for _, fp := range footprints {
+		m, ok := (*fp.HolderPtr()).(map[string]interface{})
+		if !ok {
+			j.AddWarning(fmt.Sprintf("cannot match the keys of a non-object value with %s", node.Regexp))
+			continue
+		}
+		keys := make([]string, 0)
+		for key := range m {
+			if node.Regexp.MatchString(key) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		sks := make([]SelectionKey, len(keys))
+		for i, key := range keys {
+			sks[i] = SelectionKey{key, VirtualInfo{
+				Virtual:  false,
+				RealSize: -1,
+			}}
+		}
+		result = append(result, MapFootprint{
+			Ref:           fp.HolderPtr(),
+			SelectionKeys: sks,
+			Path:          fp.HolderPath(),
+			parent:        fp.Parent(),
+		})
+	}
+	return result, nil
+}
+
+// evalGlobField selects the keys of an object matching the glob of the node in
+// sorted order, like evalKeyRegex
+func (j *Jsonpath) evalGlobField(footprints []Footprint, node *GlobFieldNode) ([]Footprint, error) {
+	footprints = expandFootprints(footprints, false)
+	result := make([]Footprint, 0)
+	for _, fp := range footprints {
+		m, ok := (*fp.HolderPtr()).(map[string]interface{})
+		if !ok {
+			j.AddWarning(fmt.Sprintf("cannot match the keys of a non-object value with %s", node.Pattern))
+			continue
+		}
+		keys := make([]string, 0)
+		for key := range m {
+			// the pattern is checked by the parser
+			if matched, _ := path.Match(node.Pattern, key); matched {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		sks := make([]SelectionKey, len(keys))
+		for i, key := range keys {
+			sks[i] = SelectionKey{key, VirtualInfo{
+				Virtual:  false,
+				RealSize: -1,
+			}}
+		}
+		result = append(result, MapFootprint{
+			Ref:           fp.HolderPtr(),
+			SelectionKeys: sks,
+			Path:          fp.HolderPath(),
+			parent:        fp.Parent(),
+		})
+	}
+	return result, nil
 }
 
 func (j *Jsonpath) evalUnion(footprints []Footprint, node *UnionNode) ([]Footprint, error) {
@@ -271,10 +586,65 @@ func (j *Jsonpath) evalUnion(footprints []Footprint, node *UnionNode) ([]Footpri
 		}
 		result = append(result, list...)
 	}
+	// a wildcard member already selects the values of the other members, so
+	// every value is selected once, in the order of the members
+	for _, n := range node.Nodes {
+		if len(n.Nodes) == 1 && n.Nodes[0].Type() == NodeWildcard {
+			return dedupeSelections(result), nil
+		}
+	}
 	return result, nil
 }
 
+// dedupeSelections removes the selections of values which are selected by a
+// previous footprint
+func dedupeSelections(footprints []Footprint) []Footprint {
+	seen := make(map[string]bool)
+	result := make([]Footprint, 0, len(footprints))
+	for _, footprint := range footprints {
+		switch fp := footprint.(type) {
+		case MapFootprint:
+			sks := make([]SelectionKey, 0, len(fp.SelectionKeys))
+			for _, sk := range fp.SelectionKeys {
+				if path := formatPath(appendPath(fp.Path, sk.Key)); !seen[path] {
+					seen[path] = true
+					sks = append(sks, sk)
+				}
+			}
+			fp.SelectionKeys = sks
+			result = append(result, fp)
+		case ArrayFootprint:
+			sis := make([]SelectionIndex, 0, len(fp.SelectionIndexes))
+			for _, si := range fp.SelectionIndexes {
+				if path := formatPath(appendPath(fp.Path, si.Index)); !seen[path] {
+					seen[path] = true
+					sis = append(sis, si)
+				}
+			}
+			fp.SelectionIndexes = sis
+			result = append(result, fp)
+		default:
+			result = append(result, footprint)
+		}
+	}
+	return result
+}
+
 func (j *Jsonpath) evalFilter(footprints []Footprint, node *FilterNode) ([]Footprint, error) {
+	// operands of a filter only read the elements, so they must never create
+	// virtual fields even when the filter itself is part of a Set
+	writeMode := j.writeMode
+	j.writeMode = false
+	defer func() {
+		j.writeMode = writeMode
+	}()
+
+	return j.filterElements(footprints, node, true)
+}
+
+// filterElements selects the elements of the selected values for which the
+// condition of the filter is pass
+func (j *Jsonpath) filterElements(footprints []Footprint, node *FilterNode, pass bool) ([]Footprint, error) {
 	footprints = expandFootprints(footprints, false)
 	result := make([]Footprint, 0)
 	for _, fp := range footprints {
@@ -285,45 +655,11 @@ func (j *Jsonpath) evalFilter(footprints []Footprint, node *FilterNode) ([]Footp
 		elements, err := allSelectedFp.Expand()
 		for _, element := range elements {
 			element = element.LeaveItAsItIs()
-			lefts, err := j.evalList([]Footprint{element}, node.Left)
-			if node.Operator == "exists" {
-				if len(lefts) > 0 {
-					result = append(result, element)
-				}
-				continue
-			}
+			matched, err := j.matchFilter(element, node)
 			if err != nil {
 				return nil, err
 			}
-			lefts = expandFootprints(lefts, true)
-
-			var left, right interface{}
-			switch {
-			case len(lefts) == 0:
-				continue
-			case len(lefts) > 1:
-				return nil, fmt.Errorf("can only compare one element at a time")
-			}
-			left = *(lefts[0].HolderPtr())
-
-			rights, err := j.evalList([]Footprint{element}, node.Right)
-			if err != nil {
-				return nil, err
-			}
-			rights = expandFootprints(rights, true)
-			switch {
-			case len(rights) == 0:
-				continue
-			case len(rights) > 1:
-				return nil, fmt.Errorf("can only compare one element at a time")
-			}
-			right = *(rights[0].HolderPtr())
-
-			pass, err := genericCompare(node.Operator, left, right)
-			if err != nil {
-				j.AddWarning(err.Error())
-			}
-			if pass {
+			if matched == pass {
 				result = append(result, element)
 			}
 		}
@@ -331,7 +667,161 @@ func (j *Jsonpath) evalFilter(footprints []Footprint, node *FilterNode) ([]Footp
 	return result, nil
 }
 
-func genericCompare(operator string, left interface{}, right interface{}) (bool, error) {
+// matchFilter reports whether an element satisfies the condition of a filter,
+// && and || combine the conditions of their operands
+func (j *Jsonpath) matchFilter(element Footprint, node *FilterNode) (bool, error) {
+	switch node.Operator {
+	case "&&", "||":
+		pass, err := j.matchFilter(element, node.Left.Nodes[0].(*FilterNode))
+		if err != nil || pass == (node.Operator == "||") {
+			return pass, err
+		}
+		return j.matchFilter(element, node.Right.Nodes[0].(*FilterNode))
+	case "!":
+		pass, err := j.matchFilter(element, node.Left.Nodes[0].(*FilterNode))
+		return !pass && err == nil, err
+	case "all", "any":
+		// all holds for no values and any does not
+		comparison := node.Left.Nodes[0].(*FilterNode)
+		lefts, err := j.selectValues(element, comparison.Left)
+		if len(lefts) == 0 || err != nil {
+			return node.Operator == "all" && err == nil, err
+		}
+		return j.compareEach(lefts, element, comparison, node.Operator == "all")
+	case "between":
+		// the bounds are the right operands of >= and <=
+		lower, upper := node.Left.Nodes[0].(*FilterNode), node.Right.Nodes[0].(*FilterNode)
+		low, ok, err := j.operandValue(element, lower.Right)
+		if !ok || err != nil {
+			return false, err
+		}
+		high, ok, err := j.operandValue(element, upper.Right)
+		if !ok || err != nil {
+			return false, err
+		}
+		if greater, err := j.genericCompare(">", low, high); err == nil && greater {
+			j.AddWarning(fmt.Sprintf("the lower bound %v of between is greater than the upper bound %v", low, high))
+			return false, nil
+		}
+		pass, err := j.matchFilter(element, lower)
+		if !pass || err != nil {
+			return false, err
+		}
+		return j.matchFilter(element, upper)
+	case "exists":
+		lefts, _ := j.evalList([]Footprint{element}, node.Left)
+		// an index or a slice selects nothing when it is out of range,
+		// so check the selected values instead of the footprints
+		return j.holds(expandFootprints(lefts, true)), nil
+	}
+
+	if j.anyMatch {
+		lefts, err := j.selectValues(element, node.Left)
+		if err != nil {
+			return false, err
+		}
+		if len(lefts) > 1 {
+			return j.compareEach(lefts, element, node, false)
+		}
+	}
+	left, ok, err := j.operandValue(element, node.Left)
+	if j.rfc && err == nil {
+		right, rightOk, err := j.operandValue(element, node.Right)
+		return err == nil && j.compareRFC(node.Operator, left, ok, right, rightOk), err
+	}
+	if !ok || err != nil {
+		return false, err
+	}
+	right, ok, err := j.operandValue(element, node.Right)
+	if !ok || err != nil {
+		return false, err
+	}
+	pass, err := j.genericCompare(node.Operator, left, right)
+	if err != nil {
+		j.AddWarning(err.Error())
+	}
+	return pass, nil
+}
+
+// compareEach compares every value of lefts with the right operand of the
+// comparison, it reports whether all of them pass, or else any of them
+func (j *Jsonpath) compareEach(lefts []interface{}, element Footprint, node *FilterNode, all bool) (bool, error) {
+	right, ok, err := j.operandValue(element, node.Right)
+	if !ok || err != nil {
+		return false, err
+	}
+	for _, left := range lefts {
+		pass, err := j.genericCompare(node.Operator, left, right)
+		if err != nil {
+			j.AddWarning(err.Error())
+		}
+		if pass != all {
+			return pass, nil
+		}
+	}
+	return all, nil
+}
+
+// operandValue evaluates an operand of a comparison on an element, ok is false
+// if the operand selects no value
+func (j *Jsonpath) operandValue(element Footprint, operand *ListNode) (value interface{}, ok bool, err error) {
+	selected, err := j.evalList([]Footprint{element}, operand)
+	if err != nil {
+		return nil, false, err
+	}
+	selected = expandFootprints(selected, true)
+	switch {
+	case len(selected) == 0:
+		return nil, false, nil
+	case len(selected) > 1:
+		return nil, false, fmt.Errorf("can only compare one element at a time")
+	}
+	return *selected[0].HolderPtr(), true, nil
+}
+
+// holds reports whether the operand of an existence filter holds, which is
+// when it selects a value, or a truthy value with SetTruthy
+func (j *Jsonpath) holds(selected []Footprint) bool {
+	if !j.truthy || j.rfc {
+		return len(selected) > 0
+	}
+	for _, s := range selected {
+		if truthy(*s.HolderPtr()) {
+			return true
+		}
+	}
+	return false
+}
+
+// truthy reports whether a value is true, a non-empty string, a non-zero
+// number or a non-empty array or object
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case float64:
+		return v != 0
+	case int:
+		return v != 0
+	case json.Number:
+		f, err := v.Float64()
+		return err == nil && f != 0
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	}
+	return value != nil
+}
+
+func (j *Jsonpath) genericCompare(operator string, left interface{}, right interface{}) (bool, error) {
+	if j.timeAware {
+		if leftTime, rightTime, ok := parseTimes(left, right); ok {
+			return compareTimes(operator, leftTime, rightTime)
+		}
+	}
 	pass := false
 	var err error
 	switch operator {
@@ -339,14 +829,20 @@ func genericCompare(operator string, left interface{}, right interface{}) (bool,
 		pass, err = template.Less(left, right)
 	case ">":
 		pass, err = template.Greater(left, right)
-	case "==":
-		pass, err = template.Equal(left, right)
-	case "!=":
-		pass, err = template.NotEqual(left, right)
+	case "==", "===":
+		pass = equalValues(left, right)
+	case "!=", "!==":
+		pass = !equalValues(left, right)
 	case "<=":
 		pass, err = template.LessEqual(left, right)
 	case ">=":
 		pass, err = template.GreaterEqual(left, right)
+	case "in":
+		pass, err = memberOf(left, right)
+	case "=~":
+		pass, err = matchRegex(left, right)
+	case "^=", "$=", "*=":
+		pass, err = matchSubstring(operator, left, right)
 	default:
 		return false, fmt.Errorf("unrecognized filter operator %s", operator)
 	}
@@ -356,29 +852,182 @@ func genericCompare(operator string, left interface{}, right interface{}) (bool,
 	return pass, nil
 }
 
+// parseTimes parses both operands as RFC3339 timestamps, ok is false unless
+// both of them are such strings
+func parseTimes(left interface{}, right interface{}) (leftTime time.Time, rightTime time.Time, ok bool) {
+	leftStr, leftOk := left.(string)
+	rightStr, rightOk := right.(string)
+	if !leftOk || !rightOk {
+		return
+	}
+	var err error
+	if leftTime, err = time.Parse(time.RFC3339, leftStr); err != nil {
+		return
+	}
+	if rightTime, err = time.Parse(time.RFC3339, rightStr); err != nil {
+		return
+	}
+	return leftTime, rightTime, true
+}
+
+// compareTimes compares the timestamps chronologically
+func compareTimes(operator string, left time.Time, right time.Time) (bool, error) {
+	switch operator {
+	case "<":
+		return left.Before(right), nil
+	case ">":
+		return left.After(right), nil
+	case "==", "===":
+		return left.Equal(right), nil
+	case "!=", "!==":
+		return !left.Equal(right), nil
+	case "<=":
+		return !left.After(right), nil
+	case ">=":
+		return !left.Before(right), nil
+	}
+	return false, fmt.Errorf("the operator %s cannot compare timestamps", operator)
+}
+
+// matchRegex reports whether the string matches the regex
+func matchRegex(value interface{}, re interface{}) (bool, error) {
+	str, ok := value.(string)
+	if !ok {
+		return false, fmt.Errorf("the left operand of =~ must be a string")
+	}
+	return re.(*regexp.Regexp).MatchString(str), nil
+}
+
+// matchSubstring reports whether the string starts with, ends with or
+// contains the other for ^=, $= and *=
+func matchSubstring(operator string, value interface{}, sub interface{}) (bool, error) {
+	str, ok := value.(string)
+	substr, subOk := sub.(string)
+	if !ok || !subOk {
+		return false, fmt.Errorf("the operands of %s must be strings", operator)
+	}
+	switch operator {
+	case "^=":
+		return strings.HasPrefix(str, substr), nil
+	case "$=":
+		return strings.HasSuffix(str, substr), nil
+	}
+	return strings.Contains(str, substr), nil
+}
+
+// equalValues compares arrays element by element and objects member by
+// member, other values are compared like template.Equal. Values of
+// different types are never equal.
+func equalValues(left interface{}, right interface{}) bool {
+	switch l := left.(type) {
+	case []interface{}:
+		r, ok := right.([]interface{})
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for i := range l {
+			if !equalValues(l[i], r[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		r, ok := right.(map[string]interface{})
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for k, v := range l {
+			w, ok := r[k]
+			if !ok {
+				return false
+			}
+			if !equalValues(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	switch right.(type) {
+	case []interface{}, map[string]interface{}:
+		return false
+	}
+	// values of different types are never equal, which is no error unlike
+	// ordering them, so 1 != "1" holds without a warning
+	equal, err := template.Equal(left, right)
+	return err == nil && equal
+}
+
+// memberOf reports whether the array holds an element equal to value.
+func memberOf(value interface{}, array interface{}) (bool, error) {
+	arr, ok := array.([]interface{})
+	if !ok {
+		return false, fmt.Errorf("the right operand of in must be an array")
+	}
+	for _, element := range arr {
+		if equal, err := template.Equal(value, element); err == nil && equal {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// evalRecursive selects every footprint itself and all of its descendants,
+// depth first. A filter following .. is applied to the children of each of
+// them, so an element nested in a matched element is matched as well. A
+// trailing .., like $.., thus selects every value at every depth including
+// the value itself, depth first. $..* selects the same values but the value
+// itself, and it selects the children of a value next to each other.
 func (j *Jsonpath) evalRecursive(footprints []Footprint, node *RecursiveNode) ([]Footprint, error) {
 	footprints = expandFootprints(footprints, false)
 	result := make([]Footprint, 0)
 	for _, footprint := range footprints {
-		recursivelyCollectFootprint(footprint, &result)
+		if err := j.recursivelyCollectFootprint(footprint, &result); err != nil {
+			return nil, err
+		}
 	}
 	return result, nil
 }
 
-func recursivelyCollectFootprint(footprint Footprint, result *[]Footprint) {
+func (j *Jsonpath) recursivelyCollectFootprint(footprint Footprint, result *[]Footprint) error {
 	*result = append(*result, footprint.LeaveItAsItIs()) // record self in result
+	if err := j.checkMaxResults(len(*result)); err != nil {
+		return err
+	}
+	if err := j.checkContext(); err != nil {
+		return err
+	}
 	var err error
 	if footprint, err = footprint.SelectAll(); err != nil {
-		return
+		return nil
 	}
 	children, _ := footprint.Expand()
 	for _, child := range children {
-		recursivelyCollectFootprint(child, result)
+		if err := j.recursivelyCollectFootprint(child, result); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// checkMaxResults reports an error when count exceeds the limit of SetMaxResults
+func (j *Jsonpath) checkMaxResults(count int) error {
+	if j.maxResults > 0 && count > j.maxResults {
+		return fmt.Errorf("too many results, the limit is %d", j.maxResults)
+	}
+	return nil
+}
+
+// checkContext reports the error of the context given to GetContext once it
+// is cancelled
+func (j *Jsonpath) checkContext() error {
+	if j.ctx == nil {
+		return nil
+	}
+	return j.ctx.Err()
 }
 
 func (j *Jsonpath) evalInt(footprints []Footprint, node *IntNode) ([]Footprint, error) {
-	footprints = expandFootprints(footprints, false)
+	footprints = expandFootprints(footprints, true)
 	result := make([]Footprint, len(footprints))
 	for i, _ := range footprints {
 		var v interface{} = node.Value
@@ -387,8 +1036,18 @@ func (j *Jsonpath) evalInt(footprints []Footprint, node *IntNode) ([]Footprint,
 	return result, nil
 }
 
+func (j *Jsonpath) evalText(footprints []Footprint, node *TextNode) ([]Footprint, error) {
+	footprints = expandFootprints(footprints, true)
+	result := make([]Footprint, len(footprints))
+	for i, _ := range footprints {
+		var v interface{} = node.Text
+		result[i] = NewFootprint(&v, nil)
+	}
+	return result, nil
+}
+
 func (j *Jsonpath) evalBool(footprints []Footprint, node *BoolNode) ([]Footprint, error) {
-	footprints = expandFootprints(footprints, false)
+	footprints = expandFootprints(footprints, true)
 	result := make([]Footprint, len(footprints))
 	for i, _ := range footprints {
 		var v interface{} = node.Value
@@ -397,8 +1056,47 @@ func (j *Jsonpath) evalBool(footprints []Footprint, node *BoolNode) ([]Footprint
 	return result, nil
 }
 
+func (j *Jsonpath) evalRegex(footprints []Footprint, node *RegexNode) ([]Footprint, error) {
+	footprints = expandFootprints(footprints, true)
+	result := make([]Footprint, len(footprints))
+	for i := range footprints {
+		var v interface{} = node.Regexp
+		result[i] = NewFootprint(&v, nil)
+	}
+	return result, nil
+}
+
+func (j *Jsonpath) evalSecondaryRoot(footprints []Footprint, node *SecondaryRootNode) ([]Footprint, error) {
+	if j.secondary == nil {
+		return nil, fmt.Errorf("cannot use $$ without a secondary document")
+	}
+	footprints = expandFootprints(footprints, true)
+	result := make([]Footprint, len(footprints))
+	for i := range footprints {
+		var holder interface{} = j.secondary
+		root, err := NewFootprint(&holder, nil).SelectAll()
+		if err != nil {
+			return nil, err
+		}
+		result[i] = root
+	}
+	return result, nil
+}
+
+func (j *Jsonpath) evalLiteral(footprints []Footprint, node *LiteralNode) ([]Footprint, error) {
+	footprints = expandFootprints(footprints, true)
+	result := make([]Footprint, len(footprints))
+	for i := range footprints {
+		var v interface{} = node.Value
+		// the literal is a value to compare, not an array or an object to
+		// select from, so it is not expanded
+		result[i] = NewFootprint(&v, nil).LeaveItAsItIs()
+	}
+	return result, nil
+}
+
 func (j *Jsonpath) evalFloat(footprints []Footprint, node *FloatNode) ([]Footprint, error) {
-	footprints = expandFootprints(footprints, false)
+	footprints = expandFootprints(footprints, true)
 	result := make([]Footprint, len(footprints))
 	for i, _ := range footprints {
 		var v interface{} = node.Value
@@ -406,3 +1104,114 @@ func (j *Jsonpath) evalFloat(footprints []Footprint, node *FloatNode) ([]Footpri
 	}
 	return result, nil
 }
+
+// selectValues evaluates a sub expression relative to the footprint, like an
+// operand of a filter, and returns the values it selects
+func (j *Jsonpath) selectValues(footprint Footprint, node *ListNode) ([]interface{}, error) {
+	selected, err := j.evalList([]Footprint{footprint.LeaveItAsItIs()}, node)
+	if err != nil {
+		return nil, err
+	}
+	selected = expandFootprints(selected, true)
+	values := make([]interface{}, len(selected))
+	for i, s := range selected {
+		values[i] = *s.HolderPtr()
+	}
+	return values, nil
+}
+
+func (j *Jsonpath) evalPseudoField(footprints []Footprint, node *PseudoFieldNode) ([]Footprint, error) {
+	footprints = expandFootprints(footprints, true)
+	result := make([]Footprint, 0)
+	for _, footprint := range footprints {
+		path := footprint.HolderPath()
+		if len(path) <= 1 { // the root is neither an element nor a member
+			continue
+		}
+		var v interface{}
+		switch last := path[len(path)-1].(type) {
+		case int:
+			if node.Name != "index" {
+				continue
+			}
+			v = last
+		case string:
+			if node.Name != "key" {
+				continue
+			}
+			v = last
+		}
+		result = append(result, NewFootprint(&v, nil))
+	}
+	return result, nil
+}
+
+// evalParent selects the object or the array holding every value, which is
+// the parent of its footprint, so the path may go on from the parent to a
+// sibling, e.g. $..price^.title. A value selected by several paths has its
+// parent selected as many times, and the root has no parent.
+func (j *Jsonpath) evalParent(footprints []Footprint, node *ParentNode) ([]Footprint, error) {
+	if j.writeMode {
+		return nil, fmt.Errorf("cannot set the value through the parent selector ^")
+	}
+	footprints = expandFootprints(footprints, true)
+	result := make([]Footprint, 0)
+	for _, footprint := range footprints {
+		// the parent of the root is the holder of the document
+		parent := footprint.Parent()
+		if parent == nil || len(parent.HolderPath()) == 0 {
+			continue
+		}
+		result = append(result, parent.LeaveItAsItIs())
+	}
+	return result, nil
+}
+
+func (j *Jsonpath) evalArithmetic(footprints []Footprint, node *ArithmeticNode) ([]Footprint, error) {
+	footprints = expandFootprints(footprints, true)
+	result := make([]Footprint, 0)
+	for _, footprint := range footprints {
+		lefts, err := j.selectValues(footprint, node.Left)
+		if err != nil {
+			return nil, err
+		}
+		rights, err := j.selectValues(footprint, node.Right)
+		if err != nil {
+			return nil, err
+		}
+		if len(lefts) != 1 || len(rights) != 1 {
+			continue
+		}
+		left, ok := toFloat(lefts[0])
+		if !ok {
+			j.AddWarning(fmt.Sprintf("the left operand of %s is not a number", node.Operator))
+			continue
+		}
+		right, ok := toFloat(rights[0])
+		if !ok || right == 0 {
+			j.AddWarning(fmt.Sprintf("the right operand of %s is not a non-zero number", node.Operator))
+			continue
+		}
+		var v interface{} = math.Mod(left, right)
+		result = append(result, NewFootprint(&v, nil))
+	}
+	return result, nil
+}
+
+// toFloat converts the numbers of a generic json object to float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		// like the number function of the templates
+		if i, err := v.Int64(); err == nil {
+			return float64(i), true
+		}
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
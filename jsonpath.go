@@ -1,8 +1,13 @@
 package jsonpath
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/zucong/jsonpath/template"
+	"reflect"
+	"sort"
+	"strings"
 )
 
 func ConvertToJsonObj(jsonStr string) interface{} {
@@ -16,12 +21,57 @@ func ConvertToJsonObj(jsonStr string) interface{} {
 	return jsonObj
 }
 
+// deepCopy copies the objects and arrays of a generic json object, scalars
+// are immutable so they are shared.
+func deepCopy(obj interface{}) interface{} {
+	switch obj := obj.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(obj))
+		for k, v := range obj {
+			m[k] = deepCopy(v)
+		}
+		return m
+	case []interface{}:
+		arr := make([]interface{}, len(obj))
+		for i, v := range obj {
+			arr[i] = deepCopy(v)
+		}
+		return arr
+	default:
+		return obj
+	}
+}
+
 type Jsonpath struct {
 	name       string
 	parser     *Parser
 	writeMode  bool
 	dataHolder []interface{}
 	warnings   []string
+	details    []WarningDetail // the warnings with their context
+	segments   []Node          // the segments being evaluated
+	segment    int             // the index of the segment being evaluated
+	maxResults int
+	timeAware  bool
+	flatten    int
+	ctx        context.Context
+	funcs      map[string]function
+	indexKeys  bool
+	strict     bool
+	skipNull   bool
+	leavesOnly bool
+	trimKeys   bool
+	lenField   bool
+	anyMatch   bool
+	polyIndex  bool
+	onWarning  func(WarningDetail)
+	truthy     bool
+	maxGrow    int
+	secondary  []interface{} // the holder of the document $$ selects
+	rfc        bool
+	safe       bool
+	dryRun     bool     // resolve the path like Set without changing the data
+	raw        *rawNode // the text of the data for GetRaw
 }
 
 func New(name string, expr string) (*Jsonpath, error) {
@@ -30,18 +80,202 @@ func New(name string, expr string) (*Jsonpath, error) {
 	}
 	p, err := Parse(j.name, "{"+expr+"}")
 	if err != nil {
-		return nil, fmt.Errorf("cannot parse jsonpath string")
+		return nil, fmt.Errorf("cannot parse jsonpath string: %v", err)
 	}
 	j.parser = p
 	return j, nil
 }
 
+// NewRelaxed is like New, but the leading $ and . of the expression are
+// optional, so store.book[0] is accepted as $.store.book[0].
+func NewRelaxed(name string, expr string) (*Jsonpath, error) {
+	return New(name, normalizeRelaxedExpr(expr))
+}
+
+func normalizeRelaxedExpr(expr string) string {
+	switch {
+	case strings.HasPrefix(expr, "$"), strings.HasPrefix(expr, "@"):
+		return expr
+	case strings.HasPrefix(expr, "."), strings.HasPrefix(expr, "["):
+		return "$" + expr
+	default:
+		return "$." + expr
+	}
+}
+
+// Clone returns a Jsonpath sharing the parsed expression, the options and the
+// secondary document but having its own data and warnings, so that clones can
+// be evaluated concurrently.
+func (j *Jsonpath) Clone() *Jsonpath {
+	return &Jsonpath{
+		name:       j.name,
+		parser:     j.parser,
+		maxResults: j.maxResults,
+		timeAware:  j.timeAware,
+		flatten:    j.flatten,
+		funcs:      j.funcs,
+		indexKeys:  j.indexKeys,
+		strict:     j.strict,
+		skipNull:   j.skipNull,
+		leavesOnly: j.leavesOnly,
+		trimKeys:   j.trimKeys,
+		lenField:   j.lenField,
+		anyMatch:   j.anyMatch,
+		polyIndex:  j.polyIndex,
+		onWarning:  j.onWarning,
+		truthy:     j.truthy,
+		maxGrow:    j.maxGrow,
+		rfc:        j.rfc,
+		safe:       j.safe,
+		secondary:  j.secondary,
+		raw:        j.raw,
+	}
+}
+
+// SetMaxResults limits how many values a recursive descent or a wildcard may
+// select, evaluating a path beyond the limit fails. 0 means unlimited.
+func (j *Jsonpath) SetMaxResults(n int) {
+	j.maxResults = n
+}
+
+// SetMaxGrow limits how many elements Set may add to an array, setting an
+// index or a slice beyond the limit fails, e.g. $[10] of an array of length 3
+// adds 8 elements. 0 means unlimited.
+func (j *Jsonpath) SetMaxGrow(n int) {
+	j.maxGrow = n
+}
+
+// SetTimeAware makes filters compare two RFC3339 timestamps chronologically
+// instead of lexically, other operands are compared as usual.
+func (j *Jsonpath) SetTimeAware(timeAware bool) {
+	j.timeAware = timeAware
+}
+
+// SetFlatten makes Get replace the results which are arrays by their
+// elements, repeatedly up to depth levels. -1 flattens the results fully and
+// 0, the default, keeps them as they are.
+func (j *Jsonpath) SetFlatten(depth int) {
+	j.flatten = depth
+}
+
+// SetIndexKeys makes a single index like [0] also select the member of an
+// object whose key is the index, which is how $..[0] finds the values of
+// documents using numeric keys, and $[2] selects what $.2 does. By default an
+// index selects elements of arrays alone, as the key of a member is a name
+// even when it is made of digits, and so $.2 selects the member "2" but $[2]
+// does not. It only applies to Get, Set never creates such a member.
+func (j *Jsonpath) SetIndexKeys(indexKeys bool) {
+	j.indexKeys = indexKeys
+}
+
+// SetPolymorphicIndex makes an index and a key select the same, whatever the
+// value holds: [0] selects the member "0" of an object like SetIndexKeys, and
+// ['0'] or .0 selects the element 0 of an array. It only applies to Get.
+func (j *Jsonpath) SetPolymorphicIndex(polyIndex bool) {
+	j.polyIndex = polyIndex
+}
+
+// SetStrict makes a path fail where it would silently select nothing, like
+// an index out of the range of an array.
+func (j *Jsonpath) SetStrict(strict bool) {
+	j.strict = strict
+}
+
+// SetSafe makes Get and the like, Exists and Stream never fail once the
+// expression is parsed: where evaluating a segment fails, like an index of a
+// number or a field of an array, the error is added as a warning and nothing
+// is selected. It is the opposite of SetStrict. The error of a context given
+// to GetContext or StreamContext is still returned, and so is the error of
+// Set.
+func (j *Jsonpath) SetSafe(safe bool) {
+	j.safe = safe
+}
+
+// SetTrimKeys makes a field name like .name select every member of an object
+// whose key equals it once both are trimmed of spaces, like " name ". By
+// default the key must equal the name exactly.
+func (j *Jsonpath) SetTrimKeys(trimKeys bool) {
+	j.trimKeys = trimKeys
+}
+
+// SetLengthPseudoField makes the field length of an array or a string select
+// its number of elements or characters, like $.items.length. An object is
+// unaffected, its length is its member named length. It only applies to Get.
+func (j *Jsonpath) SetLengthPseudoField(lenField bool) {
+	j.lenField = lenField
+}
+
+// SetAnyMatch makes a comparison whose left operand selects several values,
+// like [?(@.tags[*] == "x")], hold when any of the values satisfies it. By
+// default such a comparison fails, as a comparison takes one value.
+func (j *Jsonpath) SetAnyMatch(anyMatch bool) {
+	j.anyMatch = anyMatch
+}
+
+// SetSkipNull makes Get leave out the matched values which are null.
+func (j *Jsonpath) SetSkipNull(skipNull bool) {
+	j.skipNull = skipNull
+}
+
+// SetLeavesOnly makes Get leave out the matched values which are objects or
+// arrays, so $..* selects the scalar leaves of the data alone.
+func (j *Jsonpath) SetLeavesOnly(leavesOnly bool) {
+	j.leavesOnly = leavesOnly
+}
+
+// SetTruthy makes a filter without comparison like [?(@.enabled)] select
+// the elements whose value is truthy: true, a non-empty string, a non-zero
+// number or a non-empty array or object. By default it selects the elements
+// having the value at all.
+func (j *Jsonpath) SetTruthy(truthy bool) {
+	j.truthy = truthy
+}
+
+// AST returns the root of the parsed expression, which is a *ListNode holding
+// the nodes of the path in lexical order.
+func (j *Jsonpath) AST() Node {
+	return j.parser.Root.Nodes[0]
+}
+
 func (j *Jsonpath) AddWarning(warning string) {
 	j.warnings = append(j.warnings, warning)
+	detail := WarningDetail{Message: warning, Index: -1, Char: -1}
+	if j.segments != nil {
+		detail.Index = j.segment
+		detail.Segment, detail.Char = j.segmentText(j.segments, j.segment)
+	}
+	j.details = append(j.details, detail)
+	if j.onWarning != nil {
+		j.onWarning(detail)
+	}
 }
 
+// SetWarningHandler makes every warning also passed to handler as soon as it
+// is added, along with the segment being evaluated, e.g. to log it. The
+// warnings are still kept for GetReport.
+func (j *Jsonpath) SetWarningHandler(handler func(WarningDetail)) {
+	j.onWarning = handler
+}
+
+// InitData sets the data to evaluate. Every map[interface{}]interface{} in
+// obj, which some decoders produce, is replaced by a map[string]interface{},
+// see normalizeMaps. The replacement is done in place, so the maps and the
+// slices of obj which hold such a map are changed as well.
 func (j *Jsonpath) InitData(obj interface{}) {
-	j.dataHolder = append(j.dataHolder, obj)
+	j.raw = nil
+	j.dataHolder = append(j.dataHolder, j.normalize(obj))
+}
+
+// InitSecondaryData sets the secondary document, which expressions refer to
+// as $$, e.g. $[?(@.id == $$.targetId)] compares with a value of it.
+func (j *Jsonpath) InitSecondaryData(obj interface{}) {
+	j.secondary = []interface{}{j.normalize(obj)}
+}
+
+// normalize converts every map[interface{}]interface{} of the data to a
+// map[string]interface{}, see normalizeMaps
+func (j *Jsonpath) normalize(obj interface{}) interface{} {
+	return normalizeMaps(obj, []interface{}{0}, j.AddWarning)
 }
 
 func (j *Jsonpath) Data() interface{} {
@@ -49,8 +283,44 @@ func (j *Jsonpath) Data() interface{} {
 }
 
 func (j *Jsonpath) FindResult() ([]Footprint, error) {
+	root, segments, err := j.root()
+	if err != nil {
+		return nil, err
+	}
+	j.segments = segments
+	defer func() {
+		j.segments = nil
+	}()
+	footprints := []Footprint{root}
+	for i := range segments {
+		if footprints, err = j.walkSegment(footprints, segments, i); err != nil {
+			return nil, err
+		}
+	}
+	return footprints, nil
+}
+
+// walkSegment evaluates the segment i of the path on the footprints. With
+// SetSafe an error is added as a warning instead, and nothing is selected.
+func (j *Jsonpath) walkSegment(footprints []Footprint, segments []Node, i int) ([]Footprint, error) {
+	j.segment = i
+	footprints, err := j.walk(footprints, segments[i])
+	if err == nil {
+		return footprints, nil
+	}
+	err = j.segmentError(err, segments, i)
+	if j.safe && !j.writeMode && j.checkContext() == nil {
+		j.AddWarning(err.Error())
+		return nil, nil
+	}
+	return nil, err
+}
+
+// root returns the footprint selecting the data and the segments of the
+// expression to evaluate from it
+func (j *Jsonpath) root() (Footprint, []Node, error) {
 	if j.parser == nil {
-		return nil, fmt.Errorf("%s is an incomplete jsonpath expr", j.name)
+		return nil, nil, fmt.Errorf("%s is an incomplete jsonpath expr", j.name)
 	}
 
 	var i interface{}
@@ -58,18 +328,105 @@ func (j *Jsonpath) FindResult() ([]Footprint, error) {
 	fp := NewFootprint(&i, nil)
 	selected, err := fp.SelectAll()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
+	// a path of the root alone, like $, selects the whole data
 	node := j.parser.Root.Nodes[0]
-	if node.(*ListNode).Nodes == nil {
-		return nil, fmt.Errorf("cannot handle empty expression")
+	if node.(*ListNode).Nodes == nil && strings.TrimSpace(j.parser.input[len(leftDelim):len(j.parser.input)-len(rightDelim)]) == "" {
+		return nil, nil, fmt.Errorf("cannot handle empty expression")
+	}
+	if j.rfc {
+		if err := j.checkRFC(node.(*ListNode).Nodes); err != nil {
+			return nil, nil, err
+		}
 	}
-	footprints, err := j.evalList([]Footprint{selected}, node.(*ListNode))
+	return selected, node.(*ListNode).Nodes, nil
+}
+
+// Exists reports whether the path matches at least one value. The footprints
+// of each segment are followed one by one, so the evaluation stops at the
+// first match.
+func (j *Jsonpath) Exists() (bool, error) {
+	j.writeMode = false
+	root, segments, err := j.root()
 	if err != nil {
-		return nil, err
+		return false, err
 	}
-	return footprints, nil
+	j.segments = segments
+	defer func() {
+		j.segments = nil
+	}()
+	return j.exists([]Footprint{root}, segments, 0)
+}
+
+func (j *Jsonpath) exists(footprints []Footprint, segments []Node, i int) (bool, error) {
+	if i == len(segments) {
+		return len(expandFootprints(footprints, true)) > 0, nil
+	}
+	footprints, err := j.walkSegment(footprints, segments, i)
+	if err != nil {
+		return false, err
+	}
+	for _, footprint := range footprints {
+		for _, part := range splitSelections(footprint) {
+			found, err := j.exists([]Footprint{part}, segments, i+1)
+			if found || err != nil {
+				return found, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// splitSelections splits a footprint into footprints selecting one value each
+func splitSelections(footprint Footprint) []Footprint {
+	result := make([]Footprint, 0)
+	switch fp := footprint.(type) {
+	case MapFootprint:
+		if fp.leaveItAsItIs {
+			break
+		}
+		for _, sk := range fp.SelectionKeys {
+			fp.SelectionKeys = []SelectionKey{sk}
+			result = append(result, fp)
+		}
+		return result
+	case ArrayFootprint:
+		if fp.leaveItAsItIs {
+			break
+		}
+		for _, si := range fp.SelectionIndexes {
+			fp.SelectionIndexes = []SelectionIndex{si}
+			result = append(result, fp)
+		}
+		return result
+	}
+	return append(result, footprint)
+}
+
+// segmentError adds the text and the position of the segment which fails to
+// the error
+func (j *Jsonpath) segmentError(err error, segments []Node, i int) error {
+	text, start := j.segmentText(segments, i)
+	if start < 0 {
+		return err
+	}
+	return fmt.Errorf("%w at segment %s (char %d)", err, text, start)
+}
+
+// segmentText returns the text of the segment i and its position in the
+// expression, the position is -1 if it is unknown
+func (j *Jsonpath) segmentText(segments []Node, i int) (string, int) {
+	expr := j.parser.input[len(leftDelim) : len(j.parser.input)-len(rightDelim)]
+	start, end := int(segments[i].Position()), len(expr)
+	if i+1 < len(segments) {
+		end = int(segments[i+1].Position())
+	}
+	if start < 0 || start > end || end > len(expr) {
+		return "", -1
+	}
+	return strings.TrimSpace(expr[start:end]), start
 }
 
 func (j *Jsonpath) Get() ([]interface{}, error) {
@@ -83,11 +440,447 @@ func (j *Jsonpath) Get() ([]interface{}, error) {
 	for _, footprint := range footprints {
 		result = append(result, footprint.HolderPtr())
 	}
+	if j.flatten != 0 {
+		result = flattenResult(result, j.flatten)
+	}
+	if j.skipNull {
+		result = skipNullResult(result)
+	}
+	if j.leavesOnly {
+		result = leavesResult(result)
+	}
 	return result, nil
 }
 
+// leavesResult removes the results which are objects or arrays
+func leavesResult(result []interface{}) []interface{} {
+	kept := result[:0]
+	for _, r := range result {
+		switch (*r.(*interface{})).(type) {
+		case map[string]interface{}, []interface{}:
+		default:
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// GetPage is like Get, but it skips the first offset results and returns at
+// most limit of the others, in the order of Get. The members of an object are
+// selected in the order of their keys, so the pages of the same data never
+// overlap. The path is evaluated in full, only the results of the page are
+// taken from the selections of the last segment, unless SetFlatten,
+// SetSkipNull or SetLeavesOnly changes the results, which makes it page the
+// results of Get.
+func (j *Jsonpath) GetPage(offset, limit int) ([]interface{}, error) {
+	if offset < 0 || limit < 0 {
+		return []interface{}{}, fmt.Errorf("invalid page of offset %d and limit %d", offset, limit)
+	}
+	if j.flatten != 0 || j.skipNull || j.leavesOnly {
+		result, err := j.Get()
+		if err != nil {
+			return result, err
+		}
+		if offset > len(result) {
+			offset = len(result)
+		}
+		if limit > len(result)-offset {
+			limit = len(result) - offset
+		}
+		return result[offset : offset+limit], nil
+	}
+	j.writeMode = false
+	footprints, err := j.FindResult()
+	if err != nil {
+		return []interface{}{}, err
+	}
+	result := make([]interface{}, 0)
+	for _, footprint := range footprints {
+		if len(result) == limit {
+			break
+		}
+		for _, fp := range expandFootprints([]Footprint{footprint}, true) {
+			if offset > 0 {
+				offset--
+				continue
+			}
+			if len(result) == limit {
+				break
+			}
+			result = append(result, fp.HolderPtr())
+		}
+	}
+	return result, nil
+}
+
+// skipNullResult removes the results which are null
+func skipNullResult(result []interface{}) []interface{} {
+	kept := result[:0]
+	for _, r := range result {
+		if *r.(*interface{}) != nil {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// flattenResult replaces the results which are arrays by pointers to their
+// elements, depth is the number of levels to flatten, or -1 for all of them
+func flattenResult(result []interface{}, depth int) []interface{} {
+	if depth == 0 {
+		return result
+	}
+	flattened := make([]interface{}, 0, len(result))
+	for _, r := range result {
+		arr, ok := (*r.(*interface{})).([]interface{})
+		if !ok {
+			flattened = append(flattened, r)
+			continue
+		}
+		elements := make([]interface{}, len(arr))
+		for i := range arr {
+			elements[i] = &arr[i]
+		}
+		flattened = append(flattened, flattenResult(elements, depth-1)...)
+	}
+	return flattened
+}
+
+// GetSingle returns the only value the path matches, it is an error if the
+// path matches no value or more than one value.
+func (j *Jsonpath) GetSingle() (interface{}, error) {
+	result, err := j.Get()
+	if err != nil {
+		return nil, err
+	}
+	if len(result) != 1 {
+		return nil, fmt.Errorf("expected exactly one match, got %d", len(result))
+	}
+	return *result[0].(*interface{}), nil
+}
+
+// GetThen evaluates sub on every value the path matches, as if each of them
+// were the data, and returns all the values sub matches in order. The options
+// of the path apply to sub as well.
+func (j *Jsonpath) GetThen(sub string) ([]interface{}, error) {
+	then, err := New(j.name, sub)
+	if err != nil {
+		return nil, err
+	}
+	result, err := j.Get()
+	if err != nil {
+		return nil, err
+	}
+	s := j.Clone()
+	s.parser = then.parser
+	chained := make([]interface{}, 0)
+	for _, r := range result {
+		s.dataHolder = []interface{}{*r.(*interface{})}
+		values, err := s.Get()
+		if err != nil {
+			return nil, err
+		}
+		chained = append(chained, values...)
+	}
+	j.warnings = append(j.warnings, s.warnings...)
+	j.details = append(j.details, s.details...)
+	return chained, nil
+}
+
+// Result is the outcome of GetReport
+type Result struct {
+	Values   []interface{}
+	Warnings []WarningDetail
+}
+
+// WarningDetail is a warning along with the segment of the path which is
+// evaluated when it occurs
+type WarningDetail struct {
+	Message string
+	Segment string // the text of the segment, empty if the warning is not about a segment
+	Index   int    // the index of the segment among the segments of the path, or -1
+	Char    int    // the position of the segment in the path, or -1
+}
+
+// GetReport is like Get, but it also returns every warning the evaluation
+// adds, each with the segment where it occurs.
+func (j *Jsonpath) GetReport() (Result, error) {
+	before := len(j.details)
+	values, err := j.Get()
+	if err != nil {
+		return Result{}, err
+	}
+	for i, v := range values {
+		values[i] = *v.(*interface{})
+	}
+	warnings := append([]WarningDetail{}, j.details[before:]...)
+	return Result{Values: values, Warnings: warnings}, nil
+}
+
+// Match is a value matched by a path along with where it is found
+type Match struct {
+	Value  interface{}
+	Key    interface{} // the key or the index of the value in its parent, nil for the root
+	Parent string      // the canonical path of the parent, empty for the root
+}
+
+// GetMatches is like Get, but every match also tells the key or the index it
+// is found at and the path of its parent, e.g. for $..price the key of every
+// match is "price". The key is the last breadcrumb of the footprint, so it is
+// cheaper than rendering the full path of every match.
+func (j *Jsonpath) GetMatches() ([]Match, error) {
+	j.writeMode = false
+	footprints, err := j.FindResult()
+	if err != nil {
+		return nil, err
+	}
+	footprints = expandFootprints(footprints, true)
+	result := make([]Match, 0, len(footprints))
+	for _, footprint := range footprints {
+		m := Match{Value: *footprint.HolderPtr()}
+		if path := footprint.HolderPath(); len(path) > 1 {
+			m.Key = path[len(path)-1]
+			m.Parent = formatPath(path[:len(path)-1])
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+// GetExcluding is like Get followed by a filter, but it selects the elements
+// for which the filter does not hold, e.g. the books of $.store.book not
+// priced over 10 for the filter @.price > 10, which may also be written as
+// ?(@.price > 10) or [?(@.price > 10)].
+func (j *Jsonpath) GetExcluding(filterExpr string) ([]interface{}, error) {
+	condition := strings.TrimSpace(filterExpr)
+	if strings.HasPrefix(condition, "[") && strings.HasSuffix(condition, "]") {
+		condition = strings.TrimSpace(condition[1 : len(condition)-1])
+	}
+	if strings.HasPrefix(condition, "?(") && strings.HasSuffix(condition, ")") {
+		condition = condition[2 : len(condition)-1]
+	}
+	node, err := parseComparison(condition)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %s: %w", filterExpr, err)
+	}
+	j.writeMode = false
+	footprints, err := j.FindResult()
+	if err != nil {
+		return nil, err
+	}
+	footprints, err = j.filterElements(footprints, node, false)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]interface{}, 0)
+	for _, footprint := range expandFootprints(footprints, true) {
+		result = append(result, footprint.HolderPtr())
+	}
+	return result, nil
+}
+
+// GetCopy is like Get, but every result holds a deep copy of the matched
+// value, so changing the results never changes the data.
+func (j *Jsonpath) GetCopy() ([]interface{}, error) {
+	result, err := j.Get()
+	if err != nil {
+		return result, err
+	}
+	for i, r := range result {
+		v := deepCopy(*r.(*interface{}))
+		result[i] = &v
+	}
+	return result, nil
+}
+
+// GetInto puts the results into target, which must be a pointer, by way of
+// encoding/json, e.g. a *[]float64 for $..price. A target which is not a
+// slice or an array takes the sole result, so the path must match exactly
+// one value then.
+func (j *Jsonpath) GetInto(target interface{}) error {
+	t := reflect.TypeOf(target)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return fmt.Errorf("cannot get %s into %T, the target must be a pointer", j.name, target)
+	}
+	result, err := j.Get()
+	if err != nil {
+		return err
+	}
+	values := make([]interface{}, len(result))
+	for i, r := range result {
+		values[i] = *r.(*interface{})
+	}
+	var value interface{} = values
+	if kind := t.Elem().Kind(); kind != reflect.Slice && kind != reflect.Array {
+		if len(values) != 1 {
+			return fmt.Errorf("cannot get %d results of %s into %T, expect exactly one", len(values), j.name, target)
+		}
+		value = values[0]
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("cannot get %s into %T: %w", j.name, target, err)
+	}
+	if err := json.Unmarshal(b, target); err != nil {
+		return fmt.Errorf("cannot get %s into %T: %w", j.name, target, err)
+	}
+	return nil
+}
+
+// GetSortedBy is like Get, but the results are ordered by the value subpath
+// selects from each of them, e.g. @.price. The order is descending if desc is
+// true. Results without the value are placed last, and results which compare
+// equal keep the order of the document.
+func (j *Jsonpath) GetSortedBy(subpath string, desc bool) ([]interface{}, error) {
+	sub, err := NewRelaxed(j.name+"/"+subpath, subpath)
+	if err != nil {
+		return nil, err
+	}
+	result, err := j.Get()
+	if err != nil {
+		return result, err
+	}
+
+	keys := make([]interface{}, len(result))
+	found := make([]bool, len(result))
+	for i, r := range result {
+		s := sub.Clone()
+		s.dataHolder = []interface{}{*r.(*interface{})}
+		if values, err := s.Get(); err == nil && len(values) > 0 {
+			keys[i], found[i] = *values[0].(*interface{}), true
+		}
+	}
+
+	indexes := make([]int, len(result))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	compare := template.Less
+	if desc {
+		compare = template.Greater
+	}
+	sort.SliceStable(indexes, func(x, y int) bool {
+		ix, iy := indexes[x], indexes[y]
+		if !found[ix] || !found[iy] {
+			return found[ix] && !found[iy]
+		}
+		pass, err := compare(keys[ix], keys[iy])
+		return err == nil && pass
+	})
+
+	sorted := make([]interface{}, len(result))
+	for i, index := range indexes {
+		sorted[i] = result[index]
+	}
+	return sorted, nil
+}
+
+// Project is like Get, but every value becomes a row holding a cell for each
+// of the columns, e.g. {"title": "@.title", "price": "@.price"}. The cell is
+// the value the subpath of the column selects from the value, null when it
+// selects nothing and an array of the values when it selects several.
+func (j *Jsonpath) Project(columns map[string]string) ([]map[string]interface{}, error) {
+	subs := make(map[string]*Jsonpath, len(columns))
+	for column, subpath := range columns {
+		sub, err := NewRelaxed(j.name+"/"+subpath, subpath)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", column, err)
+		}
+		subs[column] = sub
+	}
+	result, err := j.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]map[string]interface{}, 0, len(result))
+	for _, r := range result {
+		row := make(map[string]interface{}, len(subs))
+		for column, sub := range subs {
+			s := sub.Clone()
+			s.dataHolder = []interface{}{*r.(*interface{})}
+			values, err := s.Get()
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", column, err)
+			}
+			switch len(values) {
+			case 0:
+				row[column] = nil
+			case 1:
+				row[column] = *values[0].(*interface{})
+			default:
+				cell := make([]interface{}, len(values))
+				for i, v := range values {
+					cell[i] = *v.(*interface{})
+				}
+				row[column] = cell
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// Ungrouped is the key GroupBy buckets the values it cannot group under.
+var Ungrouped interface{} = ungrouped{}
+
+type ungrouped struct{}
+
+// GroupBy is like Get, but the values are bucketed by the value subpath
+// selects from each of them, e.g. @.category, in the order of the document.
+// The key of a bucket is the value itself, so 1 and "1" are different keys.
+// A value for which subpath selects nothing, null, an object or an array is
+// bucketed under Ungrouped and a warning is added.
+func (j *Jsonpath) GroupBy(subpath string) (map[interface{}][]interface{}, error) {
+	sub, err := NewRelaxed(j.name+"/"+subpath, subpath)
+	if err != nil {
+		return nil, err
+	}
+	result, err := j.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[interface{}][]interface{})
+	for i, r := range result {
+		value := *r.(*interface{})
+		s := sub.Clone()
+		s.dataHolder = []interface{}{value}
+		key := Ungrouped
+		keys, err := s.Get()
+		switch {
+		case err != nil || len(keys) == 0:
+			j.AddWarning(fmt.Sprintf("cannot group the match %d, %s selects nothing", i, subpath))
+		default:
+			switch k := (*keys[0].(*interface{})).(type) {
+			case map[string]interface{}, []interface{}, nil:
+				j.AddWarning(fmt.Sprintf("cannot group the match %d by the non-scalar value %v", i, k))
+			default:
+				if !reflect.TypeOf(k).Comparable() {
+					j.AddWarning(fmt.Sprintf("cannot group the match %d by the non-scalar value %v", i, k))
+					break
+				}
+				key = k
+			}
+		}
+		groups[key] = append(groups[key], value)
+	}
+	return groups, nil
+}
+
+// GetContext is like Get, but the evaluation stops with ctx.Err() once ctx is
+// cancelled during recursive descents and wildcards.
+func (j *Jsonpath) GetContext(ctx context.Context) ([]interface{}, error) {
+	j.ctx = ctx
+	defer func() {
+		j.ctx = nil
+	}()
+	return j.Get()
+}
+
 func (j *Jsonpath) Set(change interface{}) error {
 	j.writeMode = true
+	j.raw = nil // the text of InitRawJSON no longer matches the data
 	footprints, err := j.FindResult()
 	if err != nil {
 		return err
@@ -102,7 +895,213 @@ func (j *Jsonpath) Set(change interface{}) error {
 	return nil
 }
 
+// SetIfAbsent is like Set, but it only writes the values which do not exist
+// yet or are null, the other values are kept.
+func (j *Jsonpath) SetIfAbsent(change interface{}) error {
+	j.writeMode = true
+	j.raw = nil
+	footprints, err := j.FindResult()
+	if err != nil {
+		return err
+	}
+
+	for _, footprint := range footprints {
+		switch fp := footprint.(type) {
+		case MapFootprint:
+			ref := (*fp.Ref).(map[string]interface{})
+			for _, sk := range fp.SelectionKeys {
+				if sk.Virtual || ref[sk.Key] == nil {
+					ref[sk.Key] = change
+				}
+			}
+		case ArrayFootprint:
+			ref := (*fp.Ref).([]interface{})
+			for _, si := range fp.SelectionIndexes {
+				if si.Virtual || ref[si.Index] == nil {
+					ref[si.Index] = change
+				}
+			}
+		default:
+			if err := footprint.UpdateAll(change); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetPreview reports the canonical paths which Set would write to, without
+// modifying the data. The path is resolved like Set does, but the arrays it
+// grows and the objects it adds members to are copied first, see detach.
+func (j *Jsonpath) SetPreview() ([]string, error) {
+	j.writeMode = true
+	j.dryRun = true
+	defer func() {
+		j.dryRun = false
+	}()
+	footprints, err := j.FindResult()
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0)
+	for _, footprint := range footprints {
+		for _, path := range selectedPaths(footprint) {
+			paths = append(paths, formatPath(path))
+		}
+	}
+	return paths, nil
+}
+
+// Plan returns the structure Set would leave the data in, without changing
+// the data. The containers Set would create are present, but the selected
+// values are not set yet.
+func (j *Jsonpath) Plan() (interface{}, error) {
+	holder := j.dataHolder
+	j.dataHolder = deepCopy(holder).([]interface{})
+	defer func() {
+		j.dataHolder = holder
+	}()
+
+	j.writeMode = true
+	footprints, err := j.FindResult()
+	if err != nil {
+		return nil, err
+	}
+	// a virtual value is only a placeholder until Set overwrites it
+	for _, footprint := range footprints {
+		switch fp := footprint.(type) {
+		case MapFootprint:
+			for _, sk := range fp.SelectionKeys {
+				if sk.Virtual {
+					fp.UpdateOne(nil, sk.Key)
+				}
+			}
+		case ArrayFootprint:
+			for _, si := range fp.SelectionIndexes {
+				if si.Virtual {
+					fp.UpdateOne(nil, si.Index)
+				}
+			}
+		}
+	}
+	return j.Data(), nil
+}
+
+// Merge deep merges partial into every object matched by the expression.
+// Nested objects are merged key by key, while scalars and arrays replace the
+// existing values. Every object gets copies of the values of partial.
+func (j *Jsonpath) Merge(partial map[string]interface{}) error {
+	j.writeMode = true
+	j.raw = nil
+	footprints, err := j.FindResult()
+	if err != nil {
+		return err
+	}
+
+	for _, footprint := range expandFootprints(footprints, true) {
+		target, ok := (*footprint.HolderPtr()).(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cannot merge into a non-object value at %s", formatPath(footprint.HolderPath()))
+		}
+		mergeObject(target, partial)
+	}
+	return nil
+}
+
+func mergeObject(target, partial map[string]interface{}) {
+	for key, value := range partial {
+		if m, ok := value.(map[string]interface{}); ok {
+			if existing, ok := target[key].(map[string]interface{}); ok {
+				mergeObject(existing, m)
+				continue
+			}
+		}
+		// a copy, so that the targets share neither the values nor partial
+		target[key] = deepCopy(value)
+	}
+}
+
+// ApplyEdits sets the value of every edit to the locations matched by its
+// jsonpath key and returns the edited document. The edits are applied in the
+// order of their keys to a deep copy of data, which is left as it is, so
+// either all of them take effect or the first failing edit is reported. An
+// edit may replace the root, like $[3] growing an array.
+func ApplyEdits(data interface{}, edits map[string]interface{}) (interface{}, error) {
+	exprs := make([]string, 0, len(edits))
+	for expr := range edits {
+		exprs = append(exprs, expr)
+	}
+	sort.Strings(exprs)
+
+	paths := make([]*Jsonpath, len(exprs))
+	for i, expr := range exprs {
+		j, err := New(expr, expr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot apply the edit %s: %v", expr, err)
+		}
+		paths[i] = j
+	}
+
+	result := deepCopy(data)
+	for i, j := range paths {
+		// the data of the first path is normalized by InitData already
+		if i == 0 {
+			j.InitData(result)
+		} else {
+			j.dataHolder = []interface{}{result}
+		}
+		if err := j.Set(edits[exprs[i]]); err != nil {
+			return nil, fmt.Errorf("cannot apply the edit %s: %v", exprs[i], err)
+		}
+		result = j.Data()
+	}
+	return result, nil
+}
+
+// GetMulti gets the values every expression selects from the same data, like
+// Get of a Jsonpath for each of them, in the order of exprs. The data is
+// initialized once and shared by the paths, which are all parsed before any
+// is evaluated, so a failing expression is reported without evaluating the
+// others.
+func GetMulti(data interface{}, exprs ...string) ([][]interface{}, error) {
+	paths := make([]*Jsonpath, len(exprs))
+	for i, expr := range exprs {
+		j, err := New(expr, expr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get %s: %v", expr, err)
+		}
+		paths[i] = j
+	}
+	if len(paths) == 0 {
+		return [][]interface{}{}, nil
+	}
+	paths[0].InitData(data)
+	for _, j := range paths[1:] {
+		j.dataHolder = paths[0].dataHolder
+	}
+	return getMulti(paths)
+}
+
+// getMulti evaluates the paths sharing their data one after another.
+func getMulti(paths []*Jsonpath) ([][]interface{}, error) {
+	result := make([][]interface{}, len(paths))
+	for i, j := range paths {
+		values, err := j.Get()
+		if err != nil {
+			return nil, fmt.Errorf("cannot get %s: %v", j.name, err)
+		}
+		result[i] = values
+	}
+	return result, nil
+}
+
 func (j *Jsonpath) walk(footprints []Footprint, node Node) ([]Footprint, error) {
+	if j.writeMode {
+		for _, footprint := range footprints {
+			j.detach(footprint)
+		}
+	}
 	switch node := node.(type) {
 	case *ListNode:
 		return j.evalList(footprints, node)
@@ -114,6 +1113,8 @@ func (j *Jsonpath) walk(footprints []Footprint, node Node) ([]Footprint, error)
 		return j.evalInt(footprints, node)
 	case *BoolNode:
 		return j.evalBool(footprints, node)
+	case *TextNode:
+		return j.evalText(footprints, node)
 	case *FloatNode:
 		return j.evalFloat(footprints, node)
 	case *WildcardNode:
@@ -126,6 +1127,26 @@ func (j *Jsonpath) walk(footprints []Footprint, node Node) ([]Footprint, error)
 		return j.evalFilter(footprints, node)
 	case *ArrayElementNode:
 		return j.evalArrayElement(footprints, node)
+	case *FunctionNode:
+		return j.evalFunction(footprints, node)
+	case *PseudoFieldNode:
+		return j.evalPseudoField(footprints, node)
+	case *ArithmeticNode:
+		return j.evalArithmetic(footprints, node)
+	case *KeyRegexNode:
+		return j.evalKeyRegex(footprints, node)
+	case *GlobFieldNode:
+		return j.evalGlobField(footprints, node)
+	case *RegexNode:
+		return j.evalRegex(footprints, node)
+	case *IndexListNode:
+		return j.evalIndexList(footprints, node)
+	case *SecondaryRootNode:
+		return j.evalSecondaryRoot(footprints, node)
+	case *LiteralNode:
+		return j.evalLiteral(footprints, node)
+	case *ParentNode:
+		return j.evalParent(footprints, node)
 	default:
 		return footprints, fmt.Errorf("unexpected Node %v", node)
 	}
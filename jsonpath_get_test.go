@@ -1,18 +1,71 @@
 package jsonpath
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 )
 
+const bookstoreData = `
+{
+  "store": {
+    "book": [
+      {
+        "category": "reference",
+        "author": "Nigel Rees",
+        "title": "Sayings of the Century",
+        "price": 8.95
+      },
+      {
+        "category": "fiction",
+        "author": "Evelyn Waugh",
+        "title": "Sword of Honour",
+        "price": 12.99
+      },
+      {
+        "category": "fiction",
+        "author": "Herman Melville",
+        "title": "Moby Dick",
+        "isbn": "0-553-21311-3",
+        "price": 8.99
+      },
+      {
+        "category": "fiction",
+        "author": "J. R. R. Tolkien",
+        "title": "The Lord of the Rings",
+        "isbn": "0-395-19395-8",
+        "price": 22.99
+      }
+    ],
+    "bicycle": {
+      "color": "red",
+      "price": 19.95
+    }
+  }
+}`
+
 type JsonpathGetCase struct {
 	name        string
 	expr        string
 	data        string
 	expectation string
 	isErrorCase bool
+	init        func(j *Jsonpath) // sets the options of the path before Get
+	ordered     bool              // the results are compared in order
+}
+
+// resultValues returns the values the results of Get and the like point to
+func resultValues(result []interface{}) []interface{} {
+	values := make([]interface{}, len(result))
+	for i, r := range result {
+		values[i] = *r.(*interface{})
+	}
+	return values
 }
 
 func LoadGetCases(cases *map[string]JsonpathGetCase) {
@@ -113,6 +166,30 @@ func LoadGetCases(cases *map[string]JsonpathGetCase) {
 		data:        `["first", "second", "third", "forth", "fifth"]`,
 		expectation: `["forth","second"]`,
 	}
+	m["Array slice with negative step stopping before index 0"] = JsonpathGetCase{
+		name:        "Array slice with negative step stopping before index 0",
+		expr:        "$[4:0:-1]",
+		data:        `["first", "second", "third", "forth", "fifth"]`,
+		expectation: `["fifth","forth","third","second"]`,
+	}
+	m["Array slice with negative step not dividing the range evenly"] = JsonpathGetCase{
+		name:        "Array slice with negative step not dividing the range evenly",
+		expr:        "$[5:0:-2]",
+		data:        `["first", "second", "third", "forth", "fifth", "sixth"]`,
+		expectation: `["sixth","forth","second"]`,
+	}
+	m["Array slice with negative start and open end and negative step"] = JsonpathGetCase{
+		name:        "Array slice with negative start and open end and negative step",
+		expr:        "$[-1::-2]",
+		data:        `["first", "second", "third", "forth", "fifth", "sixth"]`,
+		expectation: `["sixth","forth","second"]`,
+	}
+	m["Array slice with negative start and open end and negative step reaching index 0"] = JsonpathGetCase{
+		name:        "Array slice with negative start and open end and negative step reaching index 0",
+		expr:        "$[-2::-2]",
+		data:        `["first", "second", "third", "forth", "fifth", "sixth"]`,
+		expectation: `["fifth","third","first"]`,
+	}
 	m["Array slice with negative step on partially overlapping array"] = JsonpathGetCase{
 		name:        "Array slice with negative step on partially overlapping array",
 		expr:        "$[7:3:-1]",
@@ -440,6 +517,30 @@ func LoadGetCases(cases *map[string]JsonpathGetCase) {
 		data:        `{"\\":"value"}`,
 		expectation: `["value"]`,
 	}
+	m["Bracket notation with quoted escaped newline"] = JsonpathGetCase{
+		name:        "Bracket notation with quoted escaped newline",
+		expr:        `$['line\nbreak']`,
+		data:        `{"line\nbreak":1,"linenbreak":2}`,
+		expectation: `[1]`,
+	}
+	m["Bracket notation with double quoted escaped tab"] = JsonpathGetCase{
+		name:        "Bracket notation with double quoted escaped tab",
+		expr:        `$["tab\tkey"]`,
+		data:        `{"tab\tkey":1,"tabtkey":2}`,
+		expectation: `[1]`,
+	}
+	m["Bracket notation with quoted unicode escape"] = JsonpathGetCase{
+		name:        "Bracket notation with quoted unicode escape",
+		expr:        `$['\u00fc']`,
+		data:        `{"\u00fc":1,"u00fc":2}`,
+		expectation: `[1]`,
+	}
+	m["Filter expression with escaped newline in string"] = JsonpathGetCase{
+		name:        "Filter expression with escaped newline in string",
+		expr:        `$[?(@.text=="a\nb")].id`,
+		data:        `[{"id":1,"text":"a\nb"},{"id":2,"text":"anb"}]`,
+		expectation: `[1]`,
+	}
 	m["Bracket notation with quoted escaped single quote"] = JsonpathGetCase{
 		name:        "Bracket notation with quoted escaped single quote",
 		expr:        `$['\'']`,
@@ -1073,72 +1174,2421 @@ func LoadGetCases(cases *map[string]JsonpathGetCase) {
 		data:        `{"id": 2, "more": [{"id": 2}, {"more": {"id": 2}}, {"id": {"id": 2}}, [{"id": 2}]]}`,
 		expectation: `[{"id":2},{"id":2},{"id":2},{"id":2}]`,
 	}
+	m["Filter expression with string greater than"] = JsonpathGetCase{
+		name:        "Filter expression with string greater than",
+		expr:        `$.store.book[?(@.author > "H")].author`,
+		data:        bookstoreData,
+		expectation: `["Nigel Rees","Herman Melville","J. R. R. Tolkien"]`,
+	}
+	m["Filter expression with string less than"] = JsonpathGetCase{
+		name:        "Filter expression with string less than",
+		expr:        `$.store.book[?(@.author < 'H')].author`,
+		data:        bookstoreData,
+		expectation: `["Evelyn Waugh"]`,
+	}
+	m["Filter expression with string equal"] = JsonpathGetCase{
+		name:        "Filter expression with string equal",
+		expr:        `$.store.book[?(@.category == "reference")].title`,
+		data:        bookstoreData,
+		expectation: `["Sayings of the Century"]`,
+	}
+	m["Filter expression comparing string with number"] = JsonpathGetCase{
+		name:        "Filter expression comparing string with number",
+		expr:        `$.store.book[?(@.author > 1)].author`,
+		data:        bookstoreData,
+		expectation: `[]`,
+	}
+	m["Filter expression with unrecognized operator"] = JsonpathGetCase{
+		name:        "Filter expression with unrecognized operator",
+		expr:        `$[?(@.a =< 3)]`,
+		data:        `[{"a": 1}, {"a": 3}]`,
+		isErrorCase: true,
+	}
+	m["Filter expression with literal in array field"] = JsonpathGetCase{
+		name:        "Filter expression with literal in array field",
+		expr:        `$[?("admin" in @.roles)]`,
+		data:        `[{"roles": ["admin"]}, {"roles": ["user"]}, {"roles": ["user", "admin"]}, {"name": "admin"}]`,
+		expectation: `[{"roles": ["admin"]}, {"roles": ["user", "admin"]}]`,
+	}
+	m["Filter expression with field in array field"] = JsonpathGetCase{
+		name:        "Filter expression with field in array field",
+		expr:        `$[?(@.role in @.allowed)].id`,
+		data:        `[{"id": 1, "role": "a", "allowed": ["a", "b"]}, {"id": 2, "role": "c", "allowed": ["a"]}, {"id": 3, "role": 1, "allowed": [1]}]`,
+		expectation: `[1, 3]`,
+	}
+	m["Filter expression with in on non array"] = JsonpathGetCase{
+		name:        "Filter expression with in on non array",
+		expr:        `$[?("admin" in @.roles)]`,
+		data:        `[{"roles": "admin"}]`,
+		expectation: `[]`,
+	}
+	m["Filter expression with in inside quotes"] = JsonpathGetCase{
+		name:        "Filter expression with in inside quotes",
+		expr:        `$[?(@.a == "sign in now")].a`,
+		data:        `[{"a": "sign in now"}, {"a": "sign"}]`,
+		expectation: `["sign in now"]`,
+	}
+	m["Filter expression with escaped double quotes in string"] = JsonpathGetCase{
+		name:        "Filter expression with escaped double quotes in string",
+		expr:        `$[?(@.name=="say \"hi\"")].id`,
+		data:        `[{"id": 1, "name": "say \"hi\""}, {"id": 2, "name": "say hi"}, {"id": 3, "name": "say \\"}]`,
+		expectation: `[1]`,
+	}
+	m["Filter expression with escaped single quotes in string"] = JsonpathGetCase{
+		name:        "Filter expression with escaped single quotes in string",
+		expr:        `$[?(@.name=='it\'s')].id`,
+		data:        `[{"id": 1, "name": "it's"}, {"id": 2, "name": "its"}]`,
+		expectation: `[1]`,
+	}
+	m["Filter expression with escaped backslash at the end of string"] = JsonpathGetCase{
+		name:        "Filter expression with escaped backslash at the end of string",
+		expr:        `$[?(@.name=="say \\")].id`,
+		data:        `[{"id": 1, "name": "say \"hi\""}, {"id": 3, "name": "say \\"}]`,
+		expectation: `[3]`,
+	}
+	m["Filter expression with parenthesis in string"] = JsonpathGetCase{
+		name:        "Filter expression with parenthesis in string",
+		expr:        `$[?(@.name=="a)b")].id`,
+		data:        `[{"id": 1, "name": "a)b"}, {"id": 2, "name": "a"}]`,
+		expectation: `[1]`,
+	}
+	m["Filter expression with empty string"] = JsonpathGetCase{
+		name:        "Filter expression with empty string",
+		expr:        `$[?(@.note=="")].id`,
+		data:        `[{"id": 1, "note": ""}, {"id": 2, "note": " "}, {"id": 3, "note": "x"}, {"id": 4}]`,
+		expectation: `[1]`,
+	}
+	m["Filter expression with whitespace string"] = JsonpathGetCase{
+		name:        "Filter expression with whitespace string",
+		expr:        `$[?(@.note==' ')].id`,
+		data:        `[{"id": 1, "note": ""}, {"id": 2, "note": " "}, {"id": 3, "note": "x"}]`,
+		expectation: `[2]`,
+	}
+	m["Filter expression with missing right operand"] = JsonpathGetCase{
+		name:        "Filter expression with missing right operand",
+		expr:        `$[?(@.note==)]`,
+		data:        `[{"note": ""}]`,
+		isErrorCase: true,
+	}
+	m["Filter expression with index existence"] = JsonpathGetCase{
+		name:        "Filter expression with index existence",
+		expr:        `$[?(@[1])]`,
+		data:        `[[1, 2], [], [3]]`,
+		expectation: `[[1, 2]]`,
+	}
+	m["Filter expression with first index existence"] = JsonpathGetCase{
+		name:        "Filter expression with first index existence",
+		expr:        `$[?(@[0])]`,
+		data:        `[[1, 2], [], [3], {"0": 1}]`,
+		expectation: `[[1, 2], [3]]`,
+	}
+	m["Filter expression with negative index existence"] = JsonpathGetCase{
+		name:        "Filter expression with negative index existence",
+		expr:        `$[?(@[-2])]`,
+		data:        `[[1, 2], [], [3]]`,
+		expectation: `[[1, 2]]`,
+	}
+	m["Filter expression with slice existence"] = JsonpathGetCase{
+		name:        "Filter expression with slice existence",
+		expr:        `$[?(@[1:])]`,
+		data:        `[[1, 2, 3], [], [3]]`,
+		expectation: `[[1, 2, 3]]`,
+	}
 	m["Filter expression with addition"] = JsonpathGetCase{
 		name:        "Filter expression with addition",
 		expr:        `$[?(@.key+50==100)]`,
 		data:        `[{"key": 60}, {"key": 50}, {"key": 10}, {"key": -50}, {"key+50": 100}]`,
 		expectation: `[{"key+50":100}]`,
 	}
-}
-
-func TestGetFunction(t *testing.T) {
-	testCases := make(map[string]JsonpathGetCase, 0)
-	LoadGetCases(&testCases)
-	//testCases = map[string]JsonpathGetCase{"": testCases["Filter expression with boolean and operator"]}
-	caseCount, failCount := 0, 0
-	for _, c := range testCases {
-		caseCount++
-		jsonObj := ConvertToJsonObj(c.data)
-		j, err := New(c.name, c.expr)
-		if err != nil && c.isErrorCase {
-			t.Log("[✅PASS] " + c.name)
-		} else if err != nil {
-			t.Errorf("[⛔️parser error] when create jsonpath(%s)=%s: %v", c.name, c.expr, err)
-			return
-		} else {
-			j.InitData(jsonObj)
-			jsonpathResult, err := j.Get()
-			if err != nil {
-				if c.isErrorCase {
-					t.Log("[✅PASS] "+c.name, "expected error: "+err.Error())
-					continue
-				} else if !c.isErrorCase {
-					failCount++
-					t.Error("[⛔️jsonpath error]️" + err.Error() + " when " + c.name)
-					return
-				}
-			}
-			warnMsg := ""
-			if len(j.warnings) > 0 {
-				sb := strings.Builder{}
-				for i, w := range j.warnings {
-					sb.WriteString(fmt.Sprintf("%d. %s; ", i+1, w))
-				}
-				warnMsg = sb.String()
-			}
-
-			resultJsonBytes, _ := json.Marshal(jsonpathResult)
-			var result, expectation []interface{}
-			json.Unmarshal(resultJsonBytes, &result)
-			if !c.isErrorCase {
-				err = json.Unmarshal([]byte(c.expectation), &expectation)
-				if err != nil {
-					failCount++
-					t.Error("[⛔️FAIL] cannot unmarshal the expectation json of " + c.name + ": " + err.Error())
-					continue
-				}
-			}
-			if Equal(result, expectation) {
-				passMsg := fmt.Sprint("[✅PASS] " + c.name)
-				if warnMsg != "" {
-					passMsg = fmt.Sprint(passMsg + " but have a warning❗️️: " + warnMsg)
-				}
-				t.Log(passMsg)
-			} else {
-				failCount++
-				t.Error("[⛔️FAIL] " + c.name + " is not correct, the result: " + string(resultJsonBytes) + ", the expectation: " + c.expectation)
-			}
-		}
+	m["Filter expression with length compared to a field"] = JsonpathGetCase{
+		name:        "Filter expression with length compared to a field",
+		expr:        `$[?(length(@.children) == @.expectedCount)].id`,
+		data:        `[{"id": 1, "children": [1, 2], "expectedCount": 2}, {"id": 2, "children": [1], "expectedCount": 2}, {"id": 3, "children": [], "expectedCount": 0}, {"id": 4, "expectedCount": 0}]`,
+		expectation: `[1, 3]`,
+	}
+	m["Filter expression with length of string and object"] = JsonpathGetCase{
+		name:        "Filter expression with length of string and object",
+		expr:        `$[?(length(@.value) > 2)].id`,
+		data:        `[{"id": 1, "value": "ab"}, {"id": 2, "value": "abc"}, {"id": 3, "value": {"a": 1, "b": 2, "c": 3}}, {"id": 4, "value": {"a": 1}}, {"id": 5, "value": 100}]`,
+		expectation: `[2, 3]`,
+	}
+	m["Filter expression with even current index"] = JsonpathGetCase{
+		name:        "Filter expression with even current index",
+		expr:        `$[?(@index % 2 == 0)]`,
+		data:        `["a", "b", "c", "d", "e"]`,
+		expectation: `["a", "c", "e"]`,
+	}
+	m["Filter expression with current index comparison"] = JsonpathGetCase{
+		name:        "Filter expression with current index comparison",
+		expr:        `$[?(@index >= 3)].id`,
+		data:        `[{"id": 0}, {"id": 1}, {"id": 2}, {"id": 3}, {"id": 4}]`,
+		expectation: `[3, 4]`,
+	}
+	m["Filter expression with current key"] = JsonpathGetCase{
+		name:        "Filter expression with current key",
+		expr:        `$[?(@key != "internal")]`,
+		data:        `{"internal": 1, "public": 2, "shared": 3}`,
+		expectation: `[2, 3]`,
+	}
+	m["Filter expression with modulo of field"] = JsonpathGetCase{
+		name:        "Filter expression with modulo of field",
+		expr:        `$[?(@.n % 3 == 1)].n`,
+		data:        `[{"n": 1}, {"n": 2}, {"n": 4}, {"n": 6}, {"n": "x"}]`,
+		expectation: `[1, 4]`,
+	}
+	m["Filter expression after recursive descent with nested matches"] = JsonpathGetCase{
+		name:        "Filter expression after recursive descent with nested matches",
+		expr:        `$..[?(@.id==2)].name`,
+		data:        `{"id": 2, "name": "root", "children": [{"id": 2, "name": "a", "children": [{"id": 2, "name": "b", "children": [{"id": 2, "name": "c"}]}, {"id": 1, "name": "d"}]}, {"id": 3, "name": "e", "x": {"id": 2, "name": "f"}}]}`,
+		expectation: `["a", "b", "c", "f"]`,
+	}
+	m["Union with key and wildcard"] = JsonpathGetCase{
+		name:        "Union with key and wildcard",
+		expr:        `$['a',*]`,
+		data:        `{"a": 1, "b": 2}`,
+		expectation: `[1, 2]`,
+	}
+	m["Union with wildcard and index"] = JsonpathGetCase{
+		name:        "Union with wildcard and index",
+		expr:        `$[*,-1]`,
+		data:        `[5, 6, 7]`,
+		expectation: `[5, 6, 7]`,
+	}
+	m["Key regex"] = JsonpathGetCase{
+		name:        "Key regex",
+		expr:        `$.~/^config_/`,
+		data:        `{"config_a": 1, "config_b": 2, "other": 3, "my_config_c": 4}`,
+		expectation: `[1, 2]`,
+	}
+	m["Key regex with escaped slash"] = JsonpathGetCase{
+		name:        "Key regex with escaped slash",
+		expr:        `$.paths.~/^\/api\//`,
+		data:        `{"paths": {"/api/users": 1, "/web": 2, "/api/": 3}}`,
+		expectation: `[1, 3]`,
+	}
+	m["Key regex after recursive descent"] = JsonpathGetCase{
+		name:        "Key regex after recursive descent",
+		expr:        `$..~/_id$/`,
+		data:        `{"user_id": 1, "profile": {"group_id": 2, "name": "x"}, "items": [{"item_id": 3}]}`,
+		expectation: `[1, 2, 3]`,
+	}
+	m["Key regex on non object"] = JsonpathGetCase{
+		name:        "Key regex on non object",
+		expr:        `$.a.~/b/`,
+		data:        `{"a": [1, 2]}`,
+		expectation: `[]`,
+	}
+	m["Key regex which is invalid"] = JsonpathGetCase{
+		name:        "Key regex which is invalid",
+		expr:        `$.~/[/`,
+		data:        `{}`,
+		isErrorCase: true,
+	}
+	m["Dot notation with escaped dot"] = JsonpathGetCase{
+		name:        "Dot notation with escaped dot",
+		expr:        `$.a\.b`,
+		data:        `{"a.b": 1, "a": {"b": 2}, "ab": 3}`,
+		expectation: `[1]`,
+	}
+	m["Dot notation with escaped backslash"] = JsonpathGetCase{
+		name:        "Dot notation with escaped backslash",
+		expr:        `$.a\\b`,
+		data:        `{"a\\b": 1, "ab": 2}`,
+		expectation: `[1]`,
+	}
+	m["Dot notation with escaped bracket"] = JsonpathGetCase{
+		name:        "Dot notation with escaped bracket",
+		expr:        `$.a\[b`,
+		data:        `{"a[b": 1, "ab": 2}`,
+		expectation: `[1]`,
+	}
+	m["Dot notation with escaped backslash before escaped dot"] = JsonpathGetCase{
+		name:        "Dot notation with escaped backslash before escaped dot",
+		expr:        `$.a\\\.b`,
+		data:        `{"a\\.b": 1, "a.b": 2}`,
+		expectation: `[1]`,
+	}
+	m["Filter expression with strict equal alias"] = JsonpathGetCase{
+		name:        "Filter expression with strict equal alias",
+		expr:        `$[?(@.a===1)].id`,
+		data:        `[{"id": 1, "a": 1}, {"id": 2, "a": "1"}, {"id": 3, "a": 2}]`,
+		expectation: `[1]`,
+	}
+	m["Filter expression with strict not equal alias"] = JsonpathGetCase{
+		name:        "Filter expression with strict not equal alias",
+		expr:        `$[?(@.a !== 1)].id`,
+		data:        `[{"id": 1, "a": 1}, {"id": 3, "a": 2}]`,
+		expectation: `[3]`,
+	}
+	m["Filter expression with unrecognized equal operator"] = JsonpathGetCase{
+		name:        "Filter expression with unrecognized equal operator",
+		expr:        `$[?(@.a====1)]`,
+		data:        `[{"a": 1}]`,
+		isErrorCase: true,
+	}
+	m["Filter expression with regex match"] = JsonpathGetCase{
+		name:        "Filter expression with regex match",
+		expr:        `$[?(@.name =~ /^foo/)].id`,
+		data:        `[{"id": 1, "name": "FooBar"}, {"id": 2, "name": "foobar"}, {"id": 3, "name": "barfoo"}, {"id": 4, "name": 1}]`,
+		expectation: `[2]`,
+	}
+	m["Filter expression with case insensitive regex match"] = JsonpathGetCase{
+		name:        "Filter expression with case insensitive regex match",
+		expr:        `$[?(@.name =~ /^foo/i)].id`,
+		data:        `[{"id": 1, "name": "FooBar"}, {"id": 2, "name": "foobar"}, {"id": 3, "name": "barfoo"}, {"id": 4, "name": "FOO"}]`,
+		expectation: `[1, 2, 4]`,
+	}
+	m["Filter expression with multiline and dot all regex match"] = JsonpathGetCase{
+		name:        "Filter expression with multiline and dot all regex match",
+		expr:        `$[?(@.text =~ /^b.c$/ms)].id`,
+		data:        `[{"id": 1, "text": "a\nb\nc"}, {"id": 2, "text": "a\nbxc"}, {"id": 3, "text": "abxc"}]`,
+		expectation: `[1, 2]`,
+	}
+	m["Filter expression with escaped slash in regex"] = JsonpathGetCase{
+		name:        "Filter expression with escaped slash in regex",
+		expr:        `$[?(@.path =~ /^\/api\//)].id`,
+		data:        `[{"id": 1, "path": "/api/users"}, {"id": 2, "path": "/web/api/"}]`,
+		expectation: `[1]`,
+	}
+	m["Filter expression with invalid regex flag"] = JsonpathGetCase{
+		name:        "Filter expression with invalid regex flag",
+		expr:        `$[?(@.name =~ /foo/g)]`,
+		data:        `[]`,
+		isErrorCase: true,
+	}
+	m["Filter expression with regex match on non regex"] = JsonpathGetCase{
+		name:        "Filter expression with regex match on non regex",
+		expr:        `$[?(@.name =~ "foo")]`,
+		data:        `[]`,
+		isErrorCase: true,
+	}
+	m["Union with spaces"] = JsonpathGetCase{
+		name:        "Union with spaces",
+		expr:        `$[ 'a' , 'b' ]`,
+		data:        `{"a": 1, "b": 2, " a ": 3}`,
+		expectation: `[1, 2]`,
+	}
+	m["Union with tabs"] = JsonpathGetCase{
+		name:        "Union with tabs",
+		expr:        "$[\t'a',\t'b'\t]",
+		data:        `{"a": 1, "b": 2}`,
+		expectation: `[1, 2]`,
+	}
+	m["Union with spaced indexes"] = JsonpathGetCase{
+		name:        "Union with spaced indexes",
+		expr:        `$[ 0 , 2 ]`,
+		data:        `["a", "b", "c"]`,
+		expectation: `["a", "c"]`,
+	}
+	m["Union with spaces inside quotes"] = JsonpathGetCase{
+		name:        "Union with spaces inside quotes",
+		expr:        `$[ ' a ' , 'b' ]`,
+		data:        `{"a": 1, "b": 2, " a ": 3}`,
+		expectation: `[3, 2]`,
+	}
+	m["Bracket notation with spaced wildcard"] = JsonpathGetCase{
+		name:        "Bracket notation with spaced wildcard",
+		expr:        `$[ * ]`,
+		data:        `["a", "b"]`,
+		expectation: `["a", "b"]`,
+	}
+	m["Union with trailing comma"] = JsonpathGetCase{
+		name:        "Union with trailing comma",
+		expr:        `$['a',]`,
+		data:        `{"a": 1}`,
+		isErrorCase: true,
+	}
+	m["Union with leading comma"] = JsonpathGetCase{
+		name:        "Union with leading comma",
+		expr:        `$[,'a']`,
+		data:        `{"a": 1}`,
+		isErrorCase: true,
+	}
+	m["Union with empty member"] = JsonpathGetCase{
+		name:        "Union with empty member",
+		expr:        `$['a', ,'b']`,
+		data:        `{"a": 1, "b": 2}`,
+		isErrorCase: true,
+	}
+	m["Union with duplicated keys"] = JsonpathGetCase{
+		name:        "Union with duplicated keys",
+		expr:        `$['a','a']`,
+		data:        `{"a": 1, "b": 2}`,
+		expectation: `[1, 1]`,
+	}
+	m["Filter expression with unknown function"] = JsonpathGetCase{
+		name:        "Filter expression with unknown function",
+		expr:        `$[?(size(@) > 2)]`,
+		data:        `["abc"]`,
+		isErrorCase: true,
+	}
+	m["Filter expression with unclosed function call"] = JsonpathGetCase{
+		name:        "Filter expression with unclosed function call",
+		expr:        `$[?(length(@ > 2)]`,
+		data:        `["abc"]`,
+		isErrorCase: true,
+	}
+	m["Filter expression with a float without leading zero"] = JsonpathGetCase{
+		name:        "Filter expression with a float without leading zero",
+		expr:        `$[?(@.r<.5)].id`,
+		data:        `[{"id": 1, "r": 0.25}, {"id": 2, "r": 0.75}]`,
+		expectation: `[1]`,
+	}
+	m["Filter expression with a negative float without leading zero"] = JsonpathGetCase{
+		name:        "Filter expression with a negative float without leading zero",
+		expr:        `$[?(@.r > -.5)].id`,
+		data:        `[{"id": 1, "r": -0.25}, {"id": 2, "r": -0.75}]`,
+		expectation: `[1]`,
+	}
+	m["Dot notation with numeric key at the root"] = JsonpathGetCase{
+		name:        "Dot notation with numeric key at the root",
+		expr:        `.2`,
+		data:        `{"2": 5}`,
+		expectation: `[5]`,
+	}
+	m["Filter expression with a float without leading zero on the left"] = JsonpathGetCase{
+		name:        "Filter expression with a float without leading zero on the left",
+		expr:        `$[?(.5 > @.r)].id`,
+		data:        `[{"id": 1, "r": 0.25}, {"id": 2, "r": 0.75}]`,
+		expectation: `[1]`,
+	}
+	m["Filter expression comparing an array literal"] = JsonpathGetCase{
+		name:        "Filter expression comparing an array literal",
+		expr:        `$[?(@.coords == [0,0])].id`,
+		data:        `[{"id": 1, "coords": [0, 0]}, {"id": 2, "coords": [0, 1]}, {"id": 3, "coords": [0, 0, 0]}, {"id": 4, "coords": "0,0"}]`,
+		expectation: `[1]`,
+	}
+	m["Filter expression comparing an array literal in order"] = JsonpathGetCase{
+		name:        "Filter expression comparing an array literal in order",
+		expr:        `$[?(@.coords != [1, 0])].id`,
+		data:        `[{"id": 1, "coords": [0, 1]}, {"id": 2, "coords": [1, 0]}]`,
+		expectation: `[1]`,
+	}
+	m["Filter expression comparing an object literal"] = JsonpathGetCase{
+		name:        "Filter expression comparing an object literal",
+		expr:        `$[?(@.size == {"w": 2, "h": [1]})].id`,
+		data:        `[{"id": 1, "size": {"h": [1], "w": 2}}, {"id": 2, "size": {"h": [1], "w": 2, "d": 3}}]`,
+		expectation: `[1]`,
+	}
+	m["Filter expression on scalars after recursive descent"] = JsonpathGetCase{
+		name:        "Filter expression on scalars after recursive descent",
+		expr:        `$..[?(@ > 1)]`,
+		data:        `{"a": [1, 2, {"b": 3}], "c": 4}`,
+		expectation: `[2, 3, 4]`,
+	}
+	m["Last elements of an array"] = JsonpathGetCase{
+		name:        "Last elements of an array",
+		expr:        `$.items.last(3)`,
+		data:        `{"items": [1, 2, 3, 4, 5]}`,
+		expectation: `[3, 4, 5]`,
+	}
+	m["Last elements of a short array"] = JsonpathGetCase{
+		name:        "Last elements of a short array",
+		expr:        `$.items.last(3)`,
+		data:        `{"items": [1, 2]}`,
+		expectation: `[1, 2]`,
+	}
+	m["Last no element of an array"] = JsonpathGetCase{
+		name:        "Last no element of an array",
+		expr:        `$.items.last(0)`,
+		data:        `{"items": [1, 2]}`,
+		expectation: `[]`,
+	}
+	m["First elements of an array"] = JsonpathGetCase{
+		name:        "First elements of an array",
+		expr:        `$.items.first(2).id`,
+		data:        `{"items": [{"id": 1}, {"id": 2}, {"id": 3}]}`,
+		expectation: `[1, 2]`,
+	}
+	m["Array slice with start large negative number on a short array"] = JsonpathGetCase{
+		name:        "Array slice with start large negative number on a short array",
+		expr:        `$[-1000:]`,
+		data:        `[1, 2]`,
+		expectation: `[1, 2]`,
+	}
+	m["Filter expression comparing nested fields"] = JsonpathGetCase{
+		name:        "Filter expression comparing nested fields",
+		expr:        `$[?(@.a.b == @.c.d)].id`,
+		data:        `[{"id": 1, "a": {"b": 1}, "c": {"d": 1}}, {"id": 2, "a": {"b": 1}, "c": {"d": 2}}, {"id": 3, "a": {"b": 1}}, {"id": 4, "a": 1, "c": {"d": 1}}]`,
+		expectation: `[1]`,
+	}
+	m["Filter expression comparing different nested fields"] = JsonpathGetCase{
+		name:        "Filter expression comparing different nested fields",
+		expr:        `$[?(@.a.b != @.c.d)].id`,
+		data:        `[{"id": 1, "a": {"b": 1}, "c": {"d": 1}}, {"id": 2, "a": {"b": 1}, "c": {"d": 2}}, {"id": 3, "c": {"d": 2}}]`,
+		expectation: `[2]`,
+	}
+	m["Filter expression on the numbers of an array"] = JsonpathGetCase{
+		name:        "Filter expression on the numbers of an array",
+		expr:        `$[?(@ > 10)]`,
+		data:        `[5, 20, 11, "30"]`,
+		expectation: `[20, 11]`,
+	}
+	m["Filter expression on the numbers of an object"] = JsonpathGetCase{
+		name:        "Filter expression on the numbers of an object",
+		expr:        `$[?(@ > 10)]`,
+		data:        `{"a": 5, "b": 20}`,
+		expectation: `[20]`,
+	}
+	m["Filter expression on the values of every child"] = JsonpathGetCase{
+		name:        "Filter expression on the values of every child",
+		expr:        `$.*[?(@ > 10)]`,
+		data:        `{"x": [5, 20], "y": {"a": 30, "b": 1}}`,
+		expectation: `[20, 30]`,
+	}
+	m["Filter expression with existence and comparison"] = JsonpathGetCase{
+		name:        "Filter expression with existence and comparison",
+		expr:        `$[?(@.a && @.b > 1)]`,
+		data:        `[{"a": 1, "b": 2}, {"a": 1, "b": 0}, {"b": 2}]`,
+		expectation: `[{"a": 1, "b": 2}]`,
+	}
+	m["Filter expression with existence of both fields"] = JsonpathGetCase{
+		name:        "Filter expression with existence of both fields",
+		expr:        `$[?(@.a&&@.b)].id`,
+		data:        `[{"id": 1, "a": 1, "b": 2}, {"id": 2, "a": 1}, {"id": 3, "b": 2}]`,
+		expectation: `[1]`,
+	}
+	m["Filter expression with either condition"] = JsonpathGetCase{
+		name:        "Filter expression with either condition",
+		expr:        `$[?(@.a == 1 || @.b == "x")].id`,
+		data:        `[{"id": 1, "a": 1}, {"id": 2, "b": "x"}, {"id": 3, "a": 2, "b": "y"}]`,
+		expectation: `[1, 2]`,
+	}
+	m["Filter expression with grouped conditions"] = JsonpathGetCase{
+		name:        "Filter expression with grouped conditions",
+		expr:        `$[?((@.a == 1 || @.a == 2) && @.b)].id`,
+		data:        `[{"id": 1, "a": 1, "b": true}, {"id": 2, "a": 2}, {"id": 3, "a": 3, "b": true}, {"id": 4, "a": 2, "b": 0}]`,
+		expectation: `[1, 4]`,
+	}
+	m["Filter expression with && in a string"] = JsonpathGetCase{
+		name:        "Filter expression with && in a string",
+		expr:        `$[?(@.a == "x && y")].id`,
+		data:        `[{"id": 1, "a": "x && y"}, {"id": 2, "a": "x"}]`,
+		expectation: `[1]`,
+	}
+	m["Filter expression with a missing condition"] = JsonpathGetCase{
+		name:        "Filter expression with a missing condition",
+		expr:        `$[?(@.a && )]`,
+		data:        `[{"a": 1}]`,
+		isErrorCase: true,
+	}
+	m["Filter expression with between"] = JsonpathGetCase{
+		name:        "Filter expression with between",
+		expr:        `$.store.book[?(@.price between 9 and 13)].title`,
+		data:        bookstoreData,
+		expectation: `["Sword of Honour"]`,
+	}
+	m["Filter expression with between inclusive bounds"] = JsonpathGetCase{
+		name:        "Filter expression with between inclusive bounds",
+		expr:        `$[?(@ between 1 and 2.5)]`,
+		data:        `[0, 1, 2, 2.5, 3]`,
+		expectation: `[1, 2, 2.5]`,
+	}
+	m["Filter expression with between reversed bounds"] = JsonpathGetCase{
+		name:        "Filter expression with between reversed bounds",
+		expr:        `$[?(@ between 3 and 1)]`,
+		data:        `[0, 1, 2, 3]`,
+		expectation: `[]`,
+	}
+	m["Filter expression with between without and"] = JsonpathGetCase{
+		name:        "Filter expression with between without and",
+		expr:        `$[?(@ between 3)]`,
+		data:        `[0]`,
+		isErrorCase: true,
+	}
+	m["Bracket notation with unicode escape in key"] = JsonpathGetCase{
+		name:        "Bracket notation with unicode escape in key",
+		expr:        `$['caf\u00e9']`,
+		data:        `{"caf\u00e9": 1, "cafe\u0301": 2}`,
+		expectation: `[1]`,
+	}
+	m["Bracket notation with unicode escape in double quoted key"] = JsonpathGetCase{
+		name:        "Bracket notation with unicode escape in double quoted key",
+		expr:        `$["caf\u00e9"]`,
+		data:        `{"caf\u00e9": 1}`,
+		expectation: `[1]`,
+	}
+	m["Filter expression with unicode escape in string"] = JsonpathGetCase{
+		name:        "Filter expression with unicode escape in string",
+		expr:        `$[?(@.name == 'caf\u00e9')].id`,
+		data:        `[{"id": 1, "name": "caf\u00e9"}, {"id": 2, "name": "cafe"}]`,
+		expectation: `[1]`,
+	}
+	m["Key glob"] = JsonpathGetCase{
+		name:        "Key glob",
+		expr:        `$.user_*`,
+		data:        `{"user_id": 1, "user_name": "x", "email": "y"}`,
+		expectation: `[1, "x"]`,
+	}
+	m["Key glob with question mark"] = JsonpathGetCase{
+		name:        "Key glob with question mark",
+		expr:        `$..v?`,
+		data:        `{"v1": 1, "v10": 2, "a": {"v2": 3, "v": 4}}`,
+		expectation: `[1, 3]`,
+	}
+	m["Key glob with escaped star"] = JsonpathGetCase{
+		name:        "Key glob with escaped star",
+		expr:        `$.a\*`,
+		data:        `{"a*": 1, "ab": 2}`,
+		expectation: `[1]`,
+	}
+	m["Key glob on non object"] = JsonpathGetCase{
+		name:        "Key glob on non object",
+		expr:        `$.a.b*`,
+		data:        `{"a": [1, 2]}`,
+		expectation: `[]`,
+	}
+	m["Filter expression with negated existence"] = JsonpathGetCase{
+		name:        "Filter expression with negated existence",
+		expr:        `$[?(!@.a)]`,
+		data:        `[{"a": 1}, {"b": 2}, {"a": null}]`,
+		expectation: `[{"b": 2}]`,
+	}
+	m["Filter expression with negated group"] = JsonpathGetCase{
+		name:        "Filter expression with negated group",
+		expr:        `$[?(!(@.a>1 && @.b<2))]`,
+		data:        `[{"a": 2, "b": 1}, {"a": 2, "b": 3}, {"a": 0, "b": 1}]`,
+		expectation: `[{"a": 2, "b": 3}, {"a": 0, "b": 1}]`,
+	}
+	m["Filter expression with negation and conjunction"] = JsonpathGetCase{
+		name:        "Filter expression with negation and conjunction",
+		expr:        `$[?(!@.a && @.b)]`,
+		data:        `[{"a": 1, "b": 1}, {"b": 2}, {"c": 3}]`,
+		expectation: `[{"b": 2}]`,
+	}
+	m["Filter expression with double negation"] = JsonpathGetCase{
+		name:        "Filter expression with double negation",
+		expr:        `$[?(!!@.a)]`,
+		data:        `[{"a": 1}, {"b": 2}]`,
+		expectation: `[{"a": 1}]`,
+	}
+	m["Filter expression with negation of nothing"] = JsonpathGetCase{
+		name:        "Filter expression with negation of nothing",
+		expr:        `$[?(!)]`,
+		data:        `[1]`,
+		isErrorCase: true,
+	}
+	m["Filter expression with count of recursive descent"] = JsonpathGetCase{
+		name:        "Filter expression with count of recursive descent",
+		expr:        `$[?(count(@..item) > 2)].id`,
+		data:        `[{"id": 1, "item": 0, "a": {"item": 1, "b": [{"item": 2}]}}, {"id": 2, "item": 0, "a": {"item": 1}}, {"id": 3, "a": [{"item": [{"item": 1}]}, {"x": {"item": 2}}, {"item": 3}]}, {"id": 4}]`,
+		expectation: `[1, 3]`,
+	}
+	m["Filter expression with count of nothing"] = JsonpathGetCase{
+		name:        "Filter expression with count of nothing",
+		expr:        `$[?(count(@..item) == 0)].id`,
+		data:        `[{"id": 1, "item": 0}, {"id": 2}, {"id": 3, "a": "item"}]`,
+		expectation: `[2, 3]`,
+	}
+	m["Filter expression with count of a wildcard"] = JsonpathGetCase{
+		name:        "Filter expression with count of a wildcard",
+		expr:        `$[?(count(@.*) == 2)]`,
+		data:        `[{"a": 1, "b": 2}, [1, 2], [1], {"a": 1}]`,
+		expectation: `[{"a": 1, "b": 2}, [1, 2]]`,
+	}
+	m["Function count with two arguments"] = JsonpathGetCase{
+		name:        "Function count with two arguments",
+		expr:        `$[?(count(@.a, @.b) == 1)]`,
+		data:        `[{"a": 1}]`,
+		isErrorCase: true,
+	}
+	m["Nth element"] = JsonpathGetCase{
+		name:        "Nth element",
+		expr:        `$.nth(2)`,
+		data:        `[0, 1, 2, 3, 4, 5]`,
+		expectation: `[0, 2, 4]`,
+	}
+	m["Nth element with offset"] = JsonpathGetCase{
+		name:        "Nth element with offset",
+		expr:        `$.a.nth(2, 1)`,
+		data:        `{"a": [0, 1, 2, 3, 4, 5]}`,
+		expectation: `[1, 3, 5]`,
+	}
+	m["Nth element with offset beyond the array"] = JsonpathGetCase{
+		name:        "Nth element with offset beyond the array",
+		expr:        `$.nth(3,7)`,
+		data:        `[0, 1, 2, 3, 4, 5]`,
+		expectation: `[]`,
+	}
+	m["Nth element followed by a field"] = JsonpathGetCase{
+		name:        "Nth element followed by a field",
+		expr:        `$.nth(3).id`,
+		data:        `[{"id": 0}, {"id": 1}, {"id": 2}, {"id": 3}]`,
+		expectation: `[0, 3]`,
+	}
+	m["Nth element with zero step"] = JsonpathGetCase{
+		name:        "Nth element with zero step",
+		expr:        `$.nth(0)`,
+		data:        `[0, 1]`,
+		isErrorCase: true,
+	}
+	m["Nth element with negative step"] = JsonpathGetCase{
+		name:        "Nth element with negative step",
+		expr:        `$.nth(-1)`,
+		data:        `[0, 1]`,
+		isErrorCase: true,
+	}
+	m["Filter expression with all quantifier"] = JsonpathGetCase{
+		name:        "Filter expression with all quantifier",
+		expr:        `$[?(all @.scores[*] > 50)].id`,
+		data:        `[{"id": 1, "scores": [60, 70]}, {"id": 2, "scores": [60, 40]}, {"id": 3, "scores": []}, {"id": 4, "scores": [51]}, {"id": 5}]`,
+		expectation: `[1, 3, 4, 5]`,
+	}
+	m["Filter expression with any quantifier"] = JsonpathGetCase{
+		name:        "Filter expression with any quantifier",
+		expr:        `$[?(any @.scores[*] > 50)].id`,
+		data:        `[{"id": 1, "scores": [60, 70]}, {"id": 2, "scores": [60, 40]}, {"id": 3, "scores": []}, {"id": 4, "scores": [10]}, {"id": 5}]`,
+		expectation: `[1, 2]`,
+	}
+	m["Filter expression with quantifiers combined"] = JsonpathGetCase{
+		name:        "Filter expression with quantifiers combined",
+		expr:        `$[?(any @.tags[*] == "x" && all @.scores[*] >= 1)].id`,
+		data:        `[{"id": 1, "tags": ["x"], "scores": [1, 2]}, {"id": 2, "tags": ["x"], "scores": [0]}, {"id": 3, "tags": ["y"], "scores": [1]}]`,
+		expectation: `[1]`,
+	}
+	m["Filter expression with quantifier of an existence check"] = JsonpathGetCase{
+		name:        "Filter expression with quantifier of an existence check",
+		expr:        `$[?(all @.tags[*])]`,
+		data:        `[{"tags": [1]}]`,
+		isErrorCase: true,
+	}
+	m["Filter expression with field named all"] = JsonpathGetCase{
+		name:        "Filter expression with field named all",
+		expr:        `$[?(@.all > 1)].all`,
+		data:        `[{"all": 1}, {"all": 2}]`,
+		expectation: `[2]`,
+	}
+	m["Filter expression with starts with operator"] = JsonpathGetCase{
+		name:        "Filter expression with starts with operator",
+		expr:        `$[?(@.name ^= "foo")].id`,
+		data:        `[{"id": 1, "name": "foobar"}, {"id": 2, "name": "barfoo"}, {"id": 3, "name": "Foo"}, {"id": 4, "name": 1}, {"id": 5}]`,
+		expectation: `[1]`,
+	}
+	m["Filter expression with ends with operator"] = JsonpathGetCase{
+		name:        "Filter expression with ends with operator",
+		expr:        `$[?(@.name$='foo')].id`,
+		data:        `[{"id": 1, "name": "foobar"}, {"id": 2, "name": "barfoo"}, {"id": 3, "name": "foo"}]`,
+		expectation: `[2, 3]`,
+	}
+	m["Filter expression with contains operator"] = JsonpathGetCase{
+		name:        "Filter expression with contains operator",
+		expr:        `$[?(@.name *= "oba")].id`,
+		data:        `[{"id": 1, "name": "foobar"}, {"id": 2, "name": "barfoo"}, {"id": 3, "name": "oba"}]`,
+		expectation: `[1, 3]`,
+	}
+	m["Filter expression with contains operator after wildcard"] = JsonpathGetCase{
+		name:        "Filter expression with contains operator after wildcard",
+		expr:        `$[?(@.*=="x")]`,
+		data:        `[{"a": "x"}, {"a": "y"}]`,
+		expectation: `[{"a": "x"}]`,
+	}
+	m["Filter expression with starts with operator comparing with a path"] = JsonpathGetCase{
+		name:        "Filter expression with starts with operator comparing with a path",
+		expr:        `$[?(@.path ^= @.prefix)].path`,
+		data:        `[{"prefix": "/api", "path": "/api/users"}, {"prefix": "/api", "path": "/web"}]`,
+		expectation: `["/api/users"]`,
+	}
+	m["Parent selector after recursive descent"] = JsonpathGetCase{
+		name:        "Parent selector after recursive descent",
+		expr:        `$..price^.title`,
+		data:        bookstoreData,
+		expectation: `["Sayings of the Century", "Sword of Honour", "Moby Dick", "The Lord of the Rings"]`,
+	}
+	m["Parent selector of an element"] = JsonpathGetCase{
+		name:        "Parent selector of an element",
+		expr:        `$.a[1]^[0]`,
+		data:        `{"a": ["x", "y"]}`,
+		expectation: `["x"]`,
+	}
+	m["Parent selector twice"] = JsonpathGetCase{
+		name:        "Parent selector twice",
+		expr:        `$.a.b.c^^.d`,
+		data:        `{"a": {"b": {"c": 1}, "d": 2}}`,
+		expectation: `[2]`,
+	}
+	m["Parent selector of the root"] = JsonpathGetCase{
+		name:        "Parent selector of the root",
+		expr:        `$^`,
+		data:        `{"a": 1}`,
+		expectation: `[]`,
+	}
+	m["Parent selector in filter"] = JsonpathGetCase{
+		name:        "Parent selector in filter",
+		expr:        `$.store.book[?(@.isbn^.price < 10)].title`,
+		data:        bookstoreData,
+		expectation: `["Moby Dick"]`,
+	}
+	m["Dot notation with escaped caret"] = JsonpathGetCase{
+		name:        "Dot notation with escaped caret",
+		expr:        `$.a\^b`,
+		data:        `{"a^b": 1}`,
+		expectation: `[1]`,
+	}
+	m["Filter expression with modulo without spaces"] = JsonpathGetCase{
+		name:        "Filter expression with modulo without spaces",
+		expr:        `$[?(@index%2==0)]`,
+		data:        `["a", "b", "c", "d", "e"]`,
+		expectation: `["a", "c", "e"]`,
+	}
+	m["Filter expression with chained modulo"] = JsonpathGetCase{
+		name:        "Filter expression with chained modulo",
+		expr:        `$[?(@.a % 2 % 2 == 1)].a`,
+		data:        `[{"a": 3}, {"a": 4}]`,
+		expectation: `[3]`,
+	}
+	m["Filter expression with regex containing a keyword"] = JsonpathGetCase{
+		name:        "Filter expression with regex containing a keyword",
+		expr:        `$[?(@.a =~ /sign in now/)].id`,
+		data:        `[{"id": 1, "a": "please sign in now"}, {"id": 2, "a": "sign up"}]`,
+		expectation: `[1]`,
+	}
+	m["Filter expression with regex containing logical operators"] = JsonpathGetCase{
+		name:        "Filter expression with regex containing logical operators",
+		expr:        `$[?(@.a =~ /a && b|c \|\| d/ && @.id > 0)].id`,
+		data:        `[{"id": 1, "a": "a && b"}, {"id": 2, "a": "c || d"}, {"id": 3, "a": "a"}]`,
+		expectation: `[1, 2]`,
+	}
+	m["Filter expression with regex containing brackets"] = JsonpathGetCase{
+		name:        "Filter expression with regex containing brackets",
+		expr:        `$[?(@.a =~ /[(]/)].id`,
+		data:        `[{"id": 1, "a": "f(x"}, {"id": 2, "a": "x]"}]`,
+		expectation: `[1]`,
+	}
+	m["Filter expression with regex containing quotes and escaped slash"] = JsonpathGetCase{
+		name:        "Filter expression with regex containing quotes and escaped slash",
+		expr:        `$[?(@.a =~ /it's \/ ok/ || @.a =~ /"[)]/)].id`,
+		data:        `[{"id": 1, "a": "it's / ok"}, {"id": 2, "a": "\")"}, {"id": 3, "a": "its"}]`,
+		expectation: `[1, 2]`,
+	}
+	m["Recursive descent with index keys disabled"] = JsonpathGetCase{
+		name:        "Recursive descent with index keys disabled",
+		expr:        `$..[0]`,
+		data:        `[{"0": "x"}, ["y"]]`,
+		expectation: `[{"0": "x"}, "y"]`,
+	}
+	m["Recursive descent with index keys"] = JsonpathGetCase{
+		name:        "Recursive descent with index keys",
+		expr:        `$..[0]`,
+		data:        `[{"0": "x"}, ["y"]]`,
+		expectation: `[{"0": "x"}, "x", "y"]`,
+		init:        func(j *Jsonpath) { j.SetIndexKeys(true) },
+	}
+	m["Dot notation with numeric key"] = JsonpathGetCase{
+		name:        "Dot notation with numeric key",
+		expr:        `$.2`,
+		data:        `{"a": "first", "2": "second"}`,
+		expectation: `["second"]`,
+	}
+	m["Bracket notation with index on object"] = JsonpathGetCase{
+		name:        "Bracket notation with index on object",
+		expr:        `$[2]`,
+		data:        `{"a": "first", "2": "second"}`,
+		expectation: `[]`,
+	}
+	m["Dot notation with numeric key and index keys"] = JsonpathGetCase{
+		name:        "Dot notation with numeric key and index keys",
+		expr:        `$.2`,
+		data:        `{"a": "first", "2": "second"}`,
+		expectation: `["second"]`,
+		init:        func(j *Jsonpath) { j.SetIndexKeys(true) },
+	}
+	m["Bracket notation with index on object and index keys"] = JsonpathGetCase{
+		name:        "Bracket notation with index on object and index keys",
+		expr:        `$[2]`,
+		data:        `{"a": "first", "2": "second"}`,
+		expectation: `["second"]`,
+		init:        func(j *Jsonpath) { j.SetIndexKeys(true) },
+	}
+	m["Wildcard with null"] = JsonpathGetCase{
+		name:        "Wildcard with null",
+		expr:        `$[*]`,
+		data:        `[40, null, 42]`,
+		expectation: `[40, null, 42]`,
+		ordered:     true,
+	}
+	m["Wildcard with skip null"] = JsonpathGetCase{
+		name:        "Wildcard with skip null",
+		expr:        `$[*]`,
+		data:        `[40, null, 42]`,
+		expectation: `[40, 42]`,
+		init:        func(j *Jsonpath) { j.SetSkipNull(true) },
+		ordered:     true,
+	}
+	m["Recursive descent with skip null"] = JsonpathGetCase{
+		name:        "Recursive descent with skip null",
+		expr:        `$..*`,
+		data:        `[40, null, 42]`,
+		expectation: `[40, 42]`,
+		init:        func(j *Jsonpath) { j.SetSkipNull(true) },
+		ordered:     true,
+	}
+	m["Bracket notation selecting null with skip null"] = JsonpathGetCase{
+		name:        "Bracket notation selecting null with skip null",
+		expr:        `$[1]`,
+		data:        `[40, null, 42]`,
+		expectation: `[]`,
+		init:        func(j *Jsonpath) { j.SetSkipNull(true) },
+	}
+	m["Recursive descent with leaves only"] = JsonpathGetCase{
+		name:        "Recursive descent with leaves only",
+		expr:        `$..*`,
+		data:        `{"a": "x", "b": [1, {"c": true, "d": null}], "e": {"f": [[]], "g": 2.5}}`,
+		expectation: `["x", 1, true, null, 2.5]`,
+		init:        func(j *Jsonpath) { j.SetLeavesOnly(true) },
+	}
+	m["Recursive descent without leaves only"] = JsonpathGetCase{
+		name:        "Recursive descent without leaves only",
+		expr:        `$..*`,
+		data:        `{"a": "x", "b": [1, {"c": true, "d": null}], "e": {"f": [[]], "g": 2.5}}`,
+		expectation: `["x", [1, {"c": true, "d": null}], {"f": [[]], "g": 2.5}, 1, {"c": true, "d": null}, true, null, [[]], 2.5, []]`,
+	}
+	m["Wildcard with leaves only"] = JsonpathGetCase{
+		name:        "Wildcard with leaves only",
+		expr:        `$.e.*`,
+		data:        `{"a": "x", "b": [1, {"c": true, "d": null}], "e": {"f": [[]], "g": 2.5}}`,
+		expectation: `[2.5]`,
+		init:        func(j *Jsonpath) { j.SetLeavesOnly(true) },
+	}
+	m["Dot notation selecting array with leaves only"] = JsonpathGetCase{
+		name:        "Dot notation selecting array with leaves only",
+		expr:        `$.b`,
+		data:        `{"a": "x", "b": [1, {"c": true, "d": null}], "e": {"f": [[]], "g": 2.5}}`,
+		expectation: `[]`,
+		init:        func(j *Jsonpath) { j.SetLeavesOnly(true) },
+	}
+	m["Dot notation with padded key"] = JsonpathGetCase{
+		name:        "Dot notation with padded key",
+		expr:        `$.name`,
+		data:        `{" name ": 1}`,
+		expectation: `[]`,
+	}
+	m["Dot notation with padded key and trim keys"] = JsonpathGetCase{
+		name:        "Dot notation with padded key and trim keys",
+		expr:        `$.name`,
+		data:        `{" name ": 1}`,
+		expectation: `[1]`,
+		init:        func(j *Jsonpath) { j.SetTrimKeys(true) },
+	}
+	m["Bracket notation with padded name and trim keys"] = JsonpathGetCase{
+		name:        "Bracket notation with padded name and trim keys",
+		expr:        `$[' name']`,
+		data:        `{"name ": 1, "names": 2}`,
+		expectation: `[1]`,
+		init:        func(j *Jsonpath) { j.SetTrimKeys(true) },
+	}
+	m["Dot notation with exact and padded keys and trim keys"] = JsonpathGetCase{
+		name:        "Dot notation with exact and padded keys and trim keys",
+		expr:        `$.a`,
+		data:        `{"a": 1, " a": 2, "b": 3}`,
+		expectation: `[2, 1]`,
+		init:        func(j *Jsonpath) { j.SetTrimKeys(true) },
+		ordered:     true,
+	}
+	m["Dot notation with exact and padded keys"] = JsonpathGetCase{
+		name:        "Dot notation with exact and padded keys",
+		expr:        `$.a`,
+		data:        `{"a": 1, " a": 2, "b": 3}`,
+		expectation: `[1]`,
+	}
+	m["Length of array without length pseudo field"] = JsonpathGetCase{
+		name:        "Length of array without length pseudo field",
+		expr:        `$.length`,
+		data:        `[1, 2, 3]`,
+		expectation: `[]`,
+	}
+	m["Length pseudo field of array"] = JsonpathGetCase{
+		name:        "Length pseudo field of array",
+		expr:        `$.length`,
+		data:        `[1, 2, 3]`,
+		expectation: `[3]`,
+		init:        func(j *Jsonpath) { j.SetLengthPseudoField(true) },
+	}
+	m["Length pseudo field of string"] = JsonpathGetCase{
+		name:        "Length pseudo field of string",
+		expr:        `$.length`,
+		data:        `"héllo"`,
+		expectation: `[5]`,
+		init:        func(j *Jsonpath) { j.SetLengthPseudoField(true) },
+	}
+	m["Length pseudo field of object with length member"] = JsonpathGetCase{
+		name:        "Length pseudo field of object with length member",
+		expr:        `$.length`,
+		data:        `{"length": "value"}`,
+		expectation: `["value"]`,
+		init:        func(j *Jsonpath) { j.SetLengthPseudoField(true) },
+	}
+	m["Length pseudo field of object"] = JsonpathGetCase{
+		name:        "Length pseudo field of object",
+		expr:        `$.length`,
+		data:        `{"a": 1}`,
+		expectation: `[]`,
+		init:        func(j *Jsonpath) { j.SetLengthPseudoField(true) },
+	}
+	m["Length pseudo field after wildcard"] = JsonpathGetCase{
+		name:        "Length pseudo field after wildcard",
+		expr:        `$.items[*].length`,
+		data:        `{"items": [[], [1], "ab", {"length": 7}, 4]}`,
+		expectation: `[0, 1, 2, 7]`,
+		init:        func(j *Jsonpath) { j.SetLengthPseudoField(true) },
+		ordered:     true,
+	}
+	m["Length pseudo field in filter"] = JsonpathGetCase{
+		name:        "Length pseudo field in filter",
+		expr:        `$[?(@.length > 1)]`,
+		data:        `[[1], [1, 2], "abc"]`,
+		expectation: `[[1, 2], "abc"]`,
+		init:        func(j *Jsonpath) { j.SetLengthPseudoField(true) },
+		ordered:     true,
+	}
+	m["Filter expression with any match"] = JsonpathGetCase{
+		name:        "Filter expression with any match",
+		expr:        `$[?(@.tags[*] == "x")].id`,
+		data:        `[{"id": 1, "tags": ["x", "y"]}, {"id": 2, "tags": ["y", "z"]}, {"id": 3, "tags": ["x"]}, {"id": 4, "tags": []}, {"id": 5}]`,
+		expectation: `[1, 3]`,
+		init:        func(j *Jsonpath) { j.SetAnyMatch(true) },
+		ordered:     true,
+	}
+	m["Filter expression with any match not equal"] = JsonpathGetCase{
+		name:        "Filter expression with any match not equal",
+		expr:        `$[?(@.tags[*] != "x")].id`,
+		data:        `[{"id": 1, "tags": ["x", "y"]}, {"id": 2, "tags": ["y", "z"]}, {"id": 3, "tags": ["x"]}, {"id": 4, "tags": []}, {"id": 5}]`,
+		expectation: `[1, 2]`,
+		init:        func(j *Jsonpath) { j.SetAnyMatch(true) },
+		ordered:     true,
+	}
+	m["Filter expression with any match in"] = JsonpathGetCase{
+		name:        "Filter expression with any match in",
+		expr:        `$[?(@.tags[*] in ["z", "w"])].id`,
+		data:        `[{"id": 1, "tags": ["x", "y"]}, {"id": 2, "tags": ["y", "z"]}, {"id": 3, "tags": ["x"]}, {"id": 4, "tags": []}, {"id": 5}]`,
+		expectation: `[2]`,
+		init:        func(j *Jsonpath) { j.SetAnyMatch(true) },
+	}
+	m["Filter expression with any match of single value"] = JsonpathGetCase{
+		name:        "Filter expression with any match of single value",
+		expr:        `$[?(@.tags[0] == "y")].id`,
+		data:        `[{"id": 1, "tags": ["x", "y"]}, {"id": 2, "tags": ["y", "z"]}, {"id": 3, "tags": ["x"]}, {"id": 4, "tags": []}, {"id": 5}]`,
+		expectation: `[2]`,
+		init:        func(j *Jsonpath) { j.SetAnyMatch(true) },
+	}
+	m["Filter expression comparing several values without any match"] = JsonpathGetCase{
+		name:        "Filter expression comparing several values without any match",
+		expr:        `$[?(@.tags[*] == "x")]`,
+		data:        `[{"id": 1, "tags": ["x", "y"]}, {"id": 2, "tags": ["y", "z"]}, {"id": 3, "tags": ["x"]}, {"id": 4, "tags": []}, {"id": 5}]`,
+		isErrorCase: true,
+	}
+	m["Bracket notation with index on object without polymorphic index"] = JsonpathGetCase{
+		name:        "Bracket notation with index on object without polymorphic index",
+		expr:        `$[0]`,
+		data:        `{"0": "v"}`,
+		expectation: `[]`,
+		ordered:     true,
+	}
+	m["Bracket notation with index on object and polymorphic index"] = JsonpathGetCase{
+		name:        "Bracket notation with index on object and polymorphic index",
+		expr:        `$[0]`,
+		data:        `{"0": "v"}`,
+		expectation: `["v"]`,
+		init:        func(j *Jsonpath) { j.SetPolymorphicIndex(true) },
+		ordered:     true,
+	}
+	m["Bracket notation with key on array without polymorphic index"] = JsonpathGetCase{
+		name:        "Bracket notation with key on array without polymorphic index",
+		expr:        `$['0']`,
+		data:        `["a", "b"]`,
+		expectation: `[]`,
+		ordered:     true,
+	}
+	m["Bracket notation with key on array and polymorphic index"] = JsonpathGetCase{
+		name:        "Bracket notation with key on array and polymorphic index",
+		expr:        `$['0']`,
+		data:        `["a", "b"]`,
+		expectation: `["a"]`,
+		init:        func(j *Jsonpath) { j.SetPolymorphicIndex(true) },
+		ordered:     true,
+	}
+	m["Dot notation with numeric key on array and polymorphic index"] = JsonpathGetCase{
+		name:        "Dot notation with numeric key on array and polymorphic index",
+		expr:        `$.1`,
+		data:        `["a", "b"]`,
+		expectation: `["b"]`,
+		init:        func(j *Jsonpath) { j.SetPolymorphicIndex(true) },
+		ordered:     true,
+	}
+	m["Bracket notation with negative key on array and polymorphic index"] = JsonpathGetCase{
+		name:        "Bracket notation with negative key on array and polymorphic index",
+		expr:        `$['-1']`,
+		data:        `["a", "b"]`,
+		expectation: `["b"]`,
+		init:        func(j *Jsonpath) { j.SetPolymorphicIndex(true) },
+		ordered:     true,
+	}
+	m["Bracket notation with key out of range and polymorphic index"] = JsonpathGetCase{
+		name:        "Bracket notation with key out of range and polymorphic index",
+		expr:        `$['2']`,
+		data:        `["a", "b"]`,
+		expectation: `[]`,
+		init:        func(j *Jsonpath) { j.SetPolymorphicIndex(true) },
+		ordered:     true,
+	}
+	m["Bracket notation with zero padded key and polymorphic index"] = JsonpathGetCase{
+		name:        "Bracket notation with zero padded key and polymorphic index",
+		expr:        `$['01']`,
+		data:        `["a", "b"]`,
+		expectation: `[]`,
+		init:        func(j *Jsonpath) { j.SetPolymorphicIndex(true) },
+		ordered:     true,
+	}
+	m["Bracket notation with key after wildcard and polymorphic index"] = JsonpathGetCase{
+		name:        "Bracket notation with key after wildcard and polymorphic index",
+		expr:        `$[*]['1']`,
+		data:        `[["a", "b"], {"1": "c"}, "d"]`,
+		expectation: `["b", "c"]`,
+		init:        func(j *Jsonpath) { j.SetPolymorphicIndex(true) },
+		ordered:     true,
+	}
+	m["Bracket notation with index after wildcard and polymorphic index"] = JsonpathGetCase{
+		name:        "Bracket notation with index after wildcard and polymorphic index",
+		expr:        `$[*][1]`,
+		data:        `[["a", "b"], {"1": "c"}, "d"]`,
+		expectation: `["b", "c"]`,
+		init:        func(j *Jsonpath) { j.SetPolymorphicIndex(true) },
+		ordered:     true,
+	}
+	m["Filter expression with existence of falsy values"] = JsonpathGetCase{
+		name:        "Filter expression with existence of falsy values",
+		expr:        `$[?(@.enabled)].id`,
+		data:        `[{"id": 1, "enabled": true}, {"id": 2, "enabled": false}, {"id": 3, "enabled": ""}, {"id": 4, "enabled": 1}, {"id": 5, "enabled": null}, {"id": 6}]`,
+		expectation: `[1, 2, 3, 4, 5]`,
+		ordered:     true,
+	}
+	m["Filter expression with truthy values"] = JsonpathGetCase{
+		name:        "Filter expression with truthy values",
+		expr:        `$[?(@.enabled)].id`,
+		data:        `[{"id": 1, "enabled": true}, {"id": 2, "enabled": false}, {"id": 3, "enabled": ""}, {"id": 4, "enabled": 1}, {"id": 5, "enabled": null}, {"id": 6}]`,
+		expectation: `[1, 4]`,
+		init:        func(j *Jsonpath) { j.SetTruthy(true) },
+		ordered:     true,
+	}
+	m["Index list"] = JsonpathGetCase{
+		name:        "Index list",
+		expr:        `$[[2,0]]`,
+		data:        `["a", "b", "c", "d"]`,
+		expectation: `["c", "a"]`,
+		ordered:     true,
+	}
+	m["Index list with spaces and negative index"] = JsonpathGetCase{
+		name:        "Index list with spaces and negative index",
+		expr:        `$[ [ -1, 0, 9 ] ]`,
+		data:        `["a", "b", "c", "d"]`,
+		expectation: `["d", "a"]`,
+		ordered:     true,
+	}
+	m["Index list with repeated index"] = JsonpathGetCase{
+		name:        "Index list with repeated index",
+		expr:        `$[[1,1]]`,
+		data:        `["a", "b", "c", "d"]`,
+		expectation: `["b", "b"]`,
+		ordered:     true,
+	}
+	m["Index list with non-number"] = JsonpathGetCase{
+		name:        "Index list with non-number",
+		expr:        `$[[0,a]]`,
+		data:        `["a", "b", "c", "d"]`,
+		isErrorCase: true,
+	}
+	m["Index list without closing bracket"] = JsonpathGetCase{
+		name:        "Index list without closing bracket",
+		expr:        `$[[0,1]`,
+		data:        `["a", "b", "c", "d"]`,
+		isErrorCase: true,
+	}
+	m["Index list followed by garbage"] = JsonpathGetCase{
+		name:        "Index list followed by garbage",
+		expr:        `$[[0,1] x]`,
+		data:        `["a", "b", "c", "d"]`,
+		isErrorCase: true,
+	}
+	m["RFC mode filter with string comparison"] = JsonpathGetCase{
+		name:        "RFC mode filter with string comparison",
+		expr:        `$.a[?(@.b == 'kilo')]`,
+		data:        `{"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}], "o": {"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}}, "e": "f"}`,
+		expectation: `[{"b": "kilo"}]`,
+		init:        func(j *Jsonpath) { j.SetRFCMode(true) },
+	}
+	m["RFC mode filter with number comparison"] = JsonpathGetCase{
+		name:        "RFC mode filter with number comparison",
+		expr:        `$.a[?(@ > 3.5)]`,
+		data:        `{"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}], "o": {"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}}, "e": "f"}`,
+		expectation: `[5, 4, 6]`,
+		init:        func(j *Jsonpath) { j.SetRFCMode(true) },
+	}
+	m["RFC mode filter with existence"] = JsonpathGetCase{
+		name:        "RFC mode filter with existence",
+		expr:        `$.a[?(@.b)]`,
+		data:        `{"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}], "o": {"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}}, "e": "f"}`,
+		expectation: `[{"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}]`,
+		init:        func(j *Jsonpath) { j.SetRFCMode(true) },
+	}
+	m["RFC mode filter comparing nothing with nothing"] = JsonpathGetCase{
+		name:        "RFC mode filter comparing nothing with nothing",
+		expr:        `$.a[?(@.b == $.x)]`,
+		data:        `{"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}], "o": {"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}}, "e": "f"}`,
+		expectation: `[3, 5, 1, 2, 4, 6]`,
+		init:        func(j *Jsonpath) { j.SetRFCMode(true) },
+	}
+	m["RFC mode filter with nothing not equal"] = JsonpathGetCase{
+		name:        "RFC mode filter with nothing not equal",
+		expr:        `$.a[?(@.b != $.x)]`,
+		data:        `{"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}], "o": {"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}}, "e": "f"}`,
+		expectation: `[{"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}]`,
+		init:        func(j *Jsonpath) { j.SetRFCMode(true) },
+	}
+	m["RFC mode filter with different types not equal"] = JsonpathGetCase{
+		name:        "RFC mode filter with different types not equal",
+		expr:        `$.a[?(@ != 'j')]`,
+		data:        `{"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}], "o": {"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}}, "e": "f"}`,
+		expectation: `[3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}]`,
+		init:        func(j *Jsonpath) { j.SetRFCMode(true) },
+	}
+	m["RFC mode filter with different types less than"] = JsonpathGetCase{
+		name:        "RFC mode filter with different types less than",
+		expr:        `$.a[?(@ < 'k')]`,
+		data:        `{"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}], "o": {"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}}, "e": "f"}`,
+		expectation: `[]`,
+		init:        func(j *Jsonpath) { j.SetRFCMode(true) },
+	}
+	m["RFC mode recursive descent"] = JsonpathGetCase{
+		name:        "RFC mode recursive descent",
+		expr:        `$..u`,
+		data:        `{"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}], "o": {"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}}, "e": "f"}`,
+		expectation: `[6]`,
+		init:        func(j *Jsonpath) { j.SetRFCMode(true) },
+	}
+	m["RFC mode filter with less or equal and both missing"] = JsonpathGetCase{
+		name:        "RFC mode filter with less or equal and both missing",
+		expr:        `$[?(@.a <= @.b)]`,
+		data:        `[{"a": 1, "b": 2}, {"a": 3, "b": 2}, {"c": 1}, {"a": 1}]`,
+		expectation: `[{"a": 1, "b": 2}, {"c": 1}]`,
+		init:        func(j *Jsonpath) { j.SetRFCMode(true) },
+	}
+	m["RFC mode filter with greater or equal and both missing"] = JsonpathGetCase{
+		name:        "RFC mode filter with greater or equal and both missing",
+		expr:        `$[?(@.a >= @.b)]`,
+		data:        `[{"a": 1, "b": 2}, {"a": 3, "b": 2}, {"c": 1}, {"b": 1}]`,
+		expectation: `[{"a": 3, "b": 2}, {"c": 1}]`,
+		init:        func(j *Jsonpath) { j.SetRFCMode(true) },
+	}
+	m["RFC mode filter with less than and both missing"] = JsonpathGetCase{
+		name:        "RFC mode filter with less than and both missing",
+		expr:        `$[?(@.a < @.b)]`,
+		data:        `[{"a": 1, "b": 2}, {"c": 1}]`,
+		expectation: `[{"a": 1, "b": 2}]`,
+		init:        func(j *Jsonpath) { j.SetRFCMode(true) },
+	}
+	m["Parent selector in the secondary document"] = JsonpathGetCase{
+		name:        "Parent selector in the secondary document",
+		expr:        `$$.a[0].p^.name`,
+		data:        `{"a": [{"p": 1, "name": "primary"}]}`,
+		expectation: `["secondary"]`,
+		init:        func(j *Jsonpath) { j.InitSecondaryData(ConvertToJsonObj(`{"a": [{"p": 2, "name": "secondary"}]}`)) },
+	}
+	m["Parent selector of a member of the secondary document"] = JsonpathGetCase{
+		name:        "Parent selector of a member of the secondary document",
+		expr:        `$$.x^`,
+		data:        `{"x": 1}`,
+		expectation: `[{"x": 2}]`,
+		init:        func(j *Jsonpath) { j.InitSecondaryData(ConvertToJsonObj(`{"x": 2}`)) },
+	}
+}
+
+func TestGetFunction(t *testing.T) {
+	testCases := make(map[string]JsonpathGetCase, 0)
+	LoadGetCases(&testCases)
+	//testCases = map[string]JsonpathGetCase{"": testCases["Filter expression with boolean and operator"]}
+	caseCount, failCount := 0, 0
+	for _, c := range testCases {
+		caseCount++
+		jsonObj := ConvertToJsonObj(c.data)
+		j, err := New(c.name, c.expr)
+		if err != nil && c.isErrorCase {
+			t.Log("[✅PASS] " + c.name)
+		} else if err != nil {
+			t.Errorf("[⛔️parser error] when create jsonpath(%s)=%s: %v", c.name, c.expr, err)
+			return
+		} else {
+			if c.init != nil {
+				c.init(j)
+			}
+			j.InitData(jsonObj)
+			jsonpathResult, err := j.Get()
+			if err != nil {
+				if c.isErrorCase {
+					t.Log("[✅PASS] "+c.name, "expected error: "+err.Error())
+					continue
+				} else if !c.isErrorCase {
+					failCount++
+					t.Error("[⛔️jsonpath error]️" + err.Error() + " when " + c.name)
+					return
+				}
+			}
+			warnMsg := ""
+			if len(j.warnings) > 0 {
+				sb := strings.Builder{}
+				for i, w := range j.warnings {
+					sb.WriteString(fmt.Sprintf("%d. %s; ", i+1, w))
+				}
+				warnMsg = sb.String()
+			}
+
+			resultJsonBytes, _ := json.Marshal(jsonpathResult)
+			var result, expectation []interface{}
+			json.Unmarshal(resultJsonBytes, &result)
+			if !c.isErrorCase {
+				err = json.Unmarshal([]byte(c.expectation), &expectation)
+				if err != nil {
+					failCount++
+					t.Error("[⛔️FAIL] cannot unmarshal the expectation json of " + c.name + ": " + err.Error())
+					continue
+				}
+			}
+			if c.ordered && reflect.DeepEqual(result, expectation) || !c.ordered && Equal(result, expectation) {
+				passMsg := fmt.Sprint("[✅PASS] " + c.name)
+				if warnMsg != "" {
+					passMsg = fmt.Sprint(passMsg + " but have a warning❗️️: " + warnMsg)
+				}
+				t.Log(passMsg)
+			} else {
+				failCount++
+				t.Error("[⛔️FAIL] " + c.name + " is not correct, the result: " + string(resultJsonBytes) + ", the expectation: " + c.expectation)
+			}
+		}
+	}
+	t.Logf("SUMMARY: [TOTAL]=%d [✅PASS]=%d [⛔️FAIL]=%d", caseCount, caseCount-failCount, failCount)
+}
+
+func TestFilterWarnsOnMixedTypeComparison(t *testing.T) {
+	j, err := New("mixed", `$.store.book[?(@.author > 1)]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(ConvertToJsonObj(bookstoreData))
+	result, err := j.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 0 {
+		t.Errorf("expect no result when comparing string with number, got %v", result)
+	}
+	if len(j.warnings) != 4 {
+		t.Errorf("expect a warning for each book, got %v", j.warnings)
+	}
+}
+
+func TestFilterRightOperandIsRequired(t *testing.T) {
+	for _, expr := range []string{`$[?(@.note==)]`, `$[?(@.note!=  )]`, `$[?(@.note<)]`} {
+		if _, err := New(expr, expr); err == nil || !strings.Contains(err.Error(), "missing the right operand") {
+			t.Errorf("%s: expect a missing operand error, got %v", expr, err)
+		}
+	}
+}
+
+func TestFilterOperatorIsValidatedWhenParsing(t *testing.T) {
+	for _, expr := range []string{`$[?(@.a =< 3)]`, `$[?(@.a => 3)]`, `$[?(@.a <> 3)]`, `$[?(@.a = 3)]`} {
+		if _, err := New(expr, expr); err == nil || !strings.Contains(err.Error(), "unrecognized filter operator") {
+			t.Errorf("%s: expect an unrecognized operator error, got %v", expr, err)
+		}
+	}
+	for _, expr := range []string{`$[?(@.a <= 3)]`, `$[?(@.a != 3)]`, `$[?(@.a == 3)]`} {
+		if _, err := New(expr, expr); err != nil {
+			t.Errorf("%s: %v", expr, err)
+		}
+	}
+}
+
+func TestGetSortedBy(t *testing.T) {
+	data := `{"books":[
+		{"title":"a","price":10},
+		{"title":"b","price":5},
+		{"title":"c"},
+		{"title":"d","price":10},
+		{"title":"e","price":5},
+		{"title":"f","price":20}
+	]}`
+	cases := []struct {
+		subpath     string
+		desc        bool
+		expectation string
+	}{
+		{"@.price", false, "b,e,a,d,f,c"},
+		{"@.price", true, "f,a,d,b,e,c"},
+		{"price", false, "b,e,a,d,f,c"},
+		{"@.title", true, "f,e,d,c,b,a"},
+		{"@.missing", false, "a,b,c,d,e,f"},
+	}
+	for _, c := range cases {
+		j, err := New("sorted", `$.books[*]`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.InitData(ConvertToJsonObj(data))
+		result, err := j.GetSortedBy(c.subpath, c.desc)
+		if err != nil {
+			t.Errorf("%s: %v", c.subpath, err)
+			continue
+		}
+		titles := make([]string, 0)
+		for _, r := range result {
+			titles = append(titles, (*r.(*interface{})).(map[string]interface{})["title"].(string))
+		}
+		if strings.Join(titles, ",") != c.expectation {
+			t.Errorf("%s desc=%v: the order %v, the expectation %s", c.subpath, c.desc, titles, c.expectation)
+		}
+	}
+}
+
+func TestGetCopy(t *testing.T) {
+	source := `{"items":[{"tags":["a"],"meta":{"n":1}}]}`
+	data := ConvertToJsonObj(source)
+	j, err := New("copy", `$.items[0]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(data)
+	result, err := j.GetCopy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := (*result[0].(*interface{})).(map[string]interface{})
+	item["tags"].([]interface{})[0] = "changed"
+	item["meta"].(map[string]interface{})["n"] = 2
+	item["added"] = true
+	if !Equal(data, ConvertToJsonObj(source)) {
+		marshal, _ := json.Marshal(data)
+		t.Errorf("the data is modified to %s", marshal)
+	}
+}
+
+func TestFilterTimeAware(t *testing.T) {
+	data := `[
+		{"id": 1, "timestamp": "2023-01-01T01:00:00+02:00"},
+		{"id": 2, "timestamp": "2022-12-31T23:30:00Z"},
+		{"id": 3, "timestamp": "2023-01-01T00:30:00Z"},
+		{"id": 4, "timestamp": "not a time"}
+	]`
+	cases := []struct {
+		expr        string
+		timeAware   bool
+		expectation string
+	}{
+		{`$[?(@.timestamp > "2023-01-01T00:00:00Z")].id`, true, `[3, 4]`},
+		{`$[?(@.timestamp > "2023-01-01T00:00:00Z")].id`, false, `[1, 3, 4]`},
+		{`$[?(@.timestamp == "2022-12-31T23:00:00Z")].id`, true, `[1]`},
+		{`$[?(@.timestamp <= "2023-01-01T02:30:00+02:00")].id`, true, `[1, 2, 3]`},
+	}
+	for _, c := range cases {
+		j, err := New("time", c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.SetTimeAware(c.timeAware)
+		j.InitData(ConvertToJsonObj(data))
+		result, err := j.Get()
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		marshal, _ := json.Marshal(result)
+		var values []interface{}
+		json.Unmarshal(marshal, &values)
+		if !Equal(values, ConvertToJsonObj(c.expectation)) {
+			t.Errorf("%s timeAware=%v: the result %s, the expectation %s", c.expr, c.timeAware, marshal, c.expectation)
+		}
+	}
+}
+
+func TestSetFlatten(t *testing.T) {
+	data := `[[1, [2, [3]]], [4], 5, {"a": [6]}]`
+	cases := []struct {
+		depth       int
+		expectation string
+	}{
+		{0, `[[1, [2, [3]]], [4], 5, {"a": [6]}]`},
+		{1, `[1, [2, [3]], 4, 5, {"a": [6]}]`},
+		{2, `[1, 2, [3], 4, 5, {"a": [6]}]`},
+		{-1, `[1, 2, 3, 4, 5, {"a": [6]}]`},
+	}
+	for _, c := range cases {
+		j, err := New("flatten", `$.*`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.SetFlatten(c.depth)
+		j.InitData(ConvertToJsonObj(data))
+		result, err := j.Get()
+		if err != nil {
+			t.Errorf("depth %d: %v", c.depth, err)
+			continue
+		}
+		marshal, _ := json.Marshal(result)
+		if !reflect.DeepEqual(ConvertToJsonObj(string(marshal)), ConvertToJsonObj(c.expectation)) {
+			t.Errorf("depth %d: the result %s, the expectation %s", c.depth, marshal, c.expectation)
+		}
+	}
+}
+
+func TestFilterComparesJsonNumber(t *testing.T) {
+	decoder := json.NewDecoder(strings.NewReader(`[{"id": 9007199254740993}, {"id": 100}, {"id": 99.5}, {"id": 1e3}]`))
+	decoder.UseNumber()
+	var data interface{}
+	if err := decoder.Decode(&data); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		expr        string
+		expectation []string
+	}{
+		{`$[?(@.id > 100)].id`, []string{"9007199254740993", "1e3"}},
+		{`$[?(@.id == 9007199254740993)].id`, []string{"9007199254740993"}},
+		{`$[?(@.id < 100.0)].id`, []string{"99.5"}},
+		{`$[?(@.id == 1000)].id`, []string{"1e3"}},
+	}
+	for _, c := range cases {
+		j, err := New("number", c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.InitData(data)
+		result, err := j.Get()
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		ids := make([]string, 0)
+		for _, r := range result {
+			ids = append(ids, string((*r.(*interface{})).(json.Number)))
+		}
+		if !reflect.DeepEqual(ids, c.expectation) {
+			t.Errorf("%s: the result %v, the expectation %v", c.expr, ids, c.expectation)
+		}
+	}
+}
+
+func TestArithmeticOnJsonNumber(t *testing.T) {
+	decoder := json.NewDecoder(strings.NewReader(`[{"a": 3}, {"a": 4}, {"a": 5.5}, {"a": 7}]`))
+	decoder.UseNumber()
+	var data interface{}
+	if err := decoder.Decode(&data); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		expr        string
+		expectation []string
+	}{
+		{`$[?(@.a % 2 == 1)].a`, []string{"3", "7"}},
+		{`$[?(@index % 2 == 0 && @.a % 2 == 1)].a`, []string{"3"}},
+		{`$[?(@.a % @index == 1)].a`, []string{"7"}},
+		{`$[?(@.a % 2 == 1.5)].a`, []string{"5.5"}},
+	}
+	for _, c := range cases {
+		j, err := New("number", c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.InitData(data)
+		result, err := j.Get()
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		values := make([]string, 0)
+		for _, r := range result {
+			values = append(values, string((*r.(*interface{})).(json.Number)))
+		}
+		if !reflect.DeepEqual(values, c.expectation) {
+			t.Errorf("%s: the result %v, the expectation %v", c.expr, values, c.expectation)
+		}
+	}
+}
+
+func TestExists(t *testing.T) {
+	cases := []struct {
+		expr        string
+		expectation bool
+	}{
+		{`$.store.book[0].title`, true},
+		{`$.missing`, false},
+		{`$.store.book[10]`, false},
+		{`$..book[?(@.price > 20)]`, true},
+		{`$..book[?(@.price > 100)]`, false},
+		{`$.store.*.color`, true},
+		{`$.store.book[*].isbn`, true},
+		{`$.store.book[0:2].isbn`, false},
+	}
+	for _, c := range cases {
+		j, err := New(c.expr, c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.InitData(ConvertToJsonObj(bookstoreData))
+		exists, err := j.Exists()
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+		} else if exists != c.expectation {
+			t.Errorf("%s: expect %v, got %v", c.expr, c.expectation, exists)
+		}
+	}
+}
+
+func TestExistsStopsAtFirstMatch(t *testing.T) {
+	items := []string{`{"id":0}`}
+	for i := 1; i < 100; i++ {
+		items = append(items, fmt.Sprintf(`{"id":"%d"}`, i))
+	}
+	j, err := New("first", `$[*][?(@ >= 0)]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(ConvertToJsonObj("[" + strings.Join(items, ",") + "]"))
+	exists, err := j.Exists()
+	if err != nil || !exists {
+		t.Fatalf("expect a match, got %v %v", exists, err)
+	}
+	// comparing the string ids with 0 warns, but the filter is only evaluated
+	// against the members of the first element
+	if len(j.warnings) != 0 {
+		t.Errorf("expect no warnings, got %v", j.warnings)
+	}
+}
+
+func TestGetSingle(t *testing.T) {
+	cases := []struct {
+		expr        string
+		expectation interface{}
+		err         string
+	}{
+		{`$.store.bicycle.color`, "red", ""},
+		{`$.store.book[?(@.isbn == "0-395-19395-8")].price`, 22.99, ""},
+		{`$.store.missing`, nil, "expected exactly one match, got 0"},
+		{`$.store.book[*].title`, nil, "expected exactly one match, got 4"},
+	}
+	for _, c := range cases {
+		j, err := New(c.expr, c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.InitData(ConvertToJsonObj(bookstoreData))
+		value, err := j.GetSingle()
+		if c.err != "" {
+			if err == nil || err.Error() != c.err {
+				t.Errorf("%s: expect the error %q, got %v", c.expr, c.err, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+		} else if value != c.expectation {
+			t.Errorf("%s: expect %v, got %v", c.expr, c.expectation, value)
+		}
+	}
+}
+
+func TestGetStruct(t *testing.T) {
+	type item struct {
+		Name  string `json:"name"`
+		Price int    `json:"price"`
+	}
+	type order struct {
+		Name  string `json:"name"`
+		ID    int
+		Items []item `json:"items"`
+		note  string
+	}
+	data := &order{Name: "order", ID: 7, Items: []item{{"pen", 3}, {"book", 12}}, note: "hidden"}
+
+	cases := []struct {
+		expr        string
+		expectation []interface{}
+	}{
+		{`$.name`, []interface{}{"order"}},
+		{`$.ID`, []interface{}{7}},
+		{`$.note`, []interface{}{}},
+		{`$.items[1].name`, []interface{}{"book"}},
+		{`$.items[*].price`, []interface{}{3, 12}},
+		{`$.items[?(@.price > 10)].name`, []interface{}{"book"}},
+		{`$..name`, []interface{}{"order", "pen", "book"}},
+		{`$.items[0].price^.name`, []interface{}{"pen"}},
+		{`$.items[?(@.price^.name == 'book')].price`, []interface{}{12}},
+	}
+	for _, c := range cases {
+		j, err := New(c.expr, c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.InitData(data)
+		result, err := j.Get()
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		values := resultValues(result)
+		if !Equal(values, c.expectation) {
+			t.Errorf("%s: expect %v, got %v", c.expr, c.expectation, values)
+		}
+	}
+
+	j, _ := New("set", `$.name`)
+	j.InitData(data)
+	if err := j.Set("changed"); err == nil {
+		t.Errorf("expect an error setting a field of a struct")
+	}
+}
+
+func TestGetStructTags(t *testing.T) {
+	type Audit struct {
+		Created string `json:"created"`
+		Note    string `json:"note"`
+	}
+	type Base struct {
+		ID string
+	}
+	type user struct {
+		*Base
+		Audit
+		UserID int    `json:"user_id,omitempty"`
+		Email  string `json:",omitempty"`
+		Secret string `json:"-"`
+		Note   string
+	}
+	data := user{Base: &Base{ID: "u1"}, Audit: Audit{Created: "today", Note: "audit"}, UserID: 42, Email: "a@b.c", Secret: "s", Note: "user"}
+
+	cases := []struct {
+		expr        string
+		expectation []interface{}
+	}{
+		{`$.user_id`, []interface{}{42}},
+		{`$.UserID`, []interface{}{}},
+		{`$.Email`, []interface{}{"a@b.c"}},
+		{`$.Secret`, []interface{}{}},
+		{`$.created`, []interface{}{"today"}},
+		{`$.ID`, []interface{}{"u1"}},
+		{`$.Note`, []interface{}{"user"}},
+		{`$.note`, []interface{}{"audit"}},
+		{`$.*`, []interface{}{"u1", "today", "audit", 42, "a@b.c", "user"}},
+	}
+	for _, c := range cases {
+		j, err := New(c.expr, c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.InitData(data)
+		result, err := j.Get()
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		values := resultValues(result)
+		if !Equal(values, c.expectation) {
+			t.Errorf("%s: expect %v, got %v", c.expr, c.expectation, values)
+		}
+	}
+
+	j, _ := New("nil embedded", `$.ID`)
+	j.InitData(user{})
+	if result, err := j.Get(); err != nil || len(result) != 0 {
+		t.Errorf("expect no result through a nil embedded pointer, got %v, %v", result, err)
+	}
+}
+
+func TestGetMatches(t *testing.T) {
+	data := ConvertToJsonObj(`{"key": 1, "a": {"key": 2, "b": [{"key": 3}, [4]]}}`)
+	cases := []struct {
+		expr        string
+		expectation []Match
+	}{
+		{`$..key`, []Match{
+			{1.0, "key", "$"},
+			{2.0, "key", "$['a']"},
+			{3.0, "key", "$['a']['b'][0]"},
+		}},
+		{`$..b[*][0]`, []Match{
+			{4.0, 0, "$['a']['b'][1]"},
+		}},
+		{`$.a.key`, []Match{
+			{2.0, "key", "$['a']"},
+		}},
+	}
+	for _, c := range cases {
+		j, err := New(c.expr, c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.InitData(data)
+		matches, err := j.GetMatches()
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		sort.Slice(matches, func(i, k int) bool {
+			return matches[i].Parent < matches[k].Parent
+		})
+		if !reflect.DeepEqual(matches, c.expectation) {
+			t.Errorf("%s: expect %v, got %v", c.expr, c.expectation, matches)
+		}
+	}
+}
+
+func TestSetStrictIndexOutOfRange(t *testing.T) {
+	for _, expr := range []string{`$[5]`, `$[-4]`} {
+		j, err := New(expr, expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.InitData(ConvertToJsonObj(`[1, 2, 3]`))
+		result, err := j.Get()
+		if err != nil || len(result) != 0 {
+			t.Errorf("%s: expect no result, got %v, %v", expr, result, err)
+		}
+
+		j.SetStrict(true)
+		_, err = j.Get()
+		expectation := fmt.Sprintf("index %s out of range for array of length 3", expr[2:len(expr)-1])
+		if err == nil || !strings.HasPrefix(err.Error(), expectation) {
+			t.Errorf("%s: expect the error %q, got %v", expr, expectation, err)
+		}
+	}
+}
+
+func TestNegatedGroupDeMorgan(t *testing.T) {
+	data := `[{"a": 0, "b": 0}, {"a": 2, "b": 1}, {"a": 2, "b": 3}, {"a": 0, "b": 3}, {"b": 1}, {}]`
+	equivalents := [][2]string{
+		{`$[?(!(@.a > 1 && @.b < 2))]`, `$[?(!(@.a > 1) || !(@.b < 2))]`},
+		{`$[?(!(@.a > 1 || @.b < 2))]`, `$[?(!(@.a > 1) && !(@.b < 2))]`},
+		{`$[?(!(@.a || @.b))]`, `$[?(!@.a && !@.b)]`},
+	}
+	get := func(expr string) []interface{} {
+		j, err := New(expr, expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.InitData(ConvertToJsonObj(data))
+		result, err := j.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := resultValues(result)
+		return values
+	}
+	for _, e := range equivalents {
+		left, right := get(e[0]), get(e[1])
+		if !reflect.DeepEqual(left, right) {
+			t.Errorf("expect %s and %s to select the same, got %v and %v", e[0], e[1], left, right)
+		}
+	}
+	if expectation := ConvertToJsonObj(`[{"a": 0, "b": 0}, {"a": 2, "b": 3}, {"a": 0, "b": 3}, {"b": 1}, {}]`); !Equal(get(equivalents[0][0]), expectation) {
+		t.Errorf("expect %v, got %v", expectation, get(equivalents[0][0]))
+	}
+}
+
+func TestSetTrimKeys(t *testing.T) {
+	j, err := New("trim", `$.name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.SetTrimKeys(true)
+	data := ConvertToJsonObj(`{" name ": 1}`)
+	j.InitData(data)
+	if err := j.Set(2.0); err != nil {
+		t.Fatal(err)
+	}
+	if expectation := ConvertToJsonObj(`{" name ": 2}`); !reflect.DeepEqual(data, expectation) {
+		t.Errorf("expect %v, got %v", expectation, data)
+	}
+}
+
+func TestGetPage(t *testing.T) {
+	cases := []struct {
+		expr          string
+		offset, limit int
+		expectation   string
+	}{
+		{`$[*]`, 0, 3, `[0, 1, 2]`},
+		{`$[*]`, 3, 3, `[3, 4, 5]`},
+		{`$[*]`, 8, 3, `[8, 9]`},
+		{`$[*]`, 10, 3, `[]`},
+		{`$[*]`, 12, 3, `[]`},
+		{`$[*]`, 4, 0, `[]`},
+		{`$[*]`, 0, 20, `[0, 1, 2, 3, 4, 5, 6, 7, 8, 9]`},
+		{`$[::-1]`, 2, 2, `[7, 6]`},
+		{`$[?(@ % 2 == 1)]`, 1, 2, `[3, 5]`},
+		{`$[0,5,2:4]`, 1, 2, `[5, 2]`},
+	}
+	for _, c := range cases {
+		j, err := New(c.expr, c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.InitData(ConvertToJsonObj(`[0, 1, 2, 3, 4, 5, 6, 7, 8, 9]`))
+		result, err := j.GetPage(c.offset, c.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := resultValues(result)
+		if !reflect.DeepEqual(values, ConvertToJsonObj(c.expectation)) {
+			t.Errorf("%s from %d up to %d: expect %s, got %v", c.expr, c.offset, c.limit, c.expectation, values)
+		}
+	}
+
+	j, err := New("page", `$[*]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(ConvertToJsonObj(`[0, null, 2, null, 4]`))
+	j.SetSkipNull(true)
+	result, err := j.GetPage(1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 2 || *result[0].(*interface{}) != 2.0 || *result[1].(*interface{}) != 4.0 {
+		t.Errorf("expect the page of the results without null, got %v", result)
+	}
+	if _, err := j.GetPage(-1, 5); err == nil {
+		t.Errorf("expect an error for a negative offset")
+	}
+
+	j, err = New("page", `$.*`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(ConvertToJsonObj(`{"h": 7, "b": 1, "f": 5, "a": 0, "g": 6, "c": 2, "e": 4, "d": 3}`))
+	pages := make([]interface{}, 0)
+	for offset := 0; offset < 8; offset += 3 {
+		result, err := j.GetPage(offset, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, resultValues(result)...)
+	}
+	if expectation := ConvertToJsonObj(`[0, 1, 2, 3, 4, 5, 6, 7]`); !reflect.DeepEqual(pages, expectation) {
+		t.Errorf("expect the pages of the members in the order of their keys, got %v", pages)
+	}
+}
+
+func TestCompareDifferentTypes(t *testing.T) {
+	data := `[{"a": 1}, {"a": "1"}, {"a": 2}, {"a": null}, {"a": true}, {"a": [1]}]`
+	cases := []struct {
+		expr        string
+		expectation string
+		warns       bool
+	}{
+		{`$[?(@.a != 1)]`, `[{"a": "1"}, {"a": 2}, {"a": null}, {"a": true}, {"a": [1]}]`, false},
+		{`$[?(@.a == 1)]`, `[{"a": 1}]`, false},
+		{`$[?(@.a == "1")]`, `[{"a": "1"}]`, false},
+		{`$[?(@.a !== "1")]`, `[{"a": 1}, {"a": 2}, {"a": null}, {"a": true}, {"a": [1]}]`, false},
+		{`$[?(@.a < 2)]`, `[{"a": 1}]`, true},
+	}
+	for _, c := range cases {
+		j, err := New(c.expr, c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.InitData(ConvertToJsonObj(data))
+		result, err := j.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := resultValues(result)
+		if !reflect.DeepEqual(values, ConvertToJsonObj(c.expectation)) {
+			t.Errorf("%s: expect %s, got %v", c.expr, c.expectation, values)
+		}
+		if warns := len(j.warnings) > 0; warns != c.warns {
+			t.Errorf("%s: expect warnings %v, got %v", c.expr, c.warns, j.warnings)
+		}
+	}
+}
+
+func TestTrailingRecursiveDescent(t *testing.T) {
+	data := `["first", {"key": ["first nested", {"more": [{"nested": ["deepest", "second"]}, ["more", "values"]]}]}]`
+	get := func(expr string) []interface{} {
+		j, err := New(expr, expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.InitData(ConvertToJsonObj(data))
+		result, err := j.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := resultValues(result)
+		return values
+	}
+	// $.. selects what $..* does and the root, depth first, where $..* selects
+	// the children of a value together
+	all := get(`$..`)
+	if expectation := append(get(`$`), get(`$..*`)...); !Equal(all, expectation) || reflect.DeepEqual(all, expectation) {
+		t.Errorf("expect $.. to select the root and $..* in another order, got %v", all)
+	}
+	if len(all) != 14 || !reflect.DeepEqual(all[1], "first") || !reflect.DeepEqual(all[13], "values") {
+		t.Errorf("expect 14 values depth first, got %d: %v", len(all), all)
+	}
+	nested := get(`$[1].key[1]..`)
+	if expectation := ConvertToJsonObj(`[{"more": [{"nested": ["deepest", "second"]}, ["more", "values"]]}, [{"nested": ["deepest", "second"]}, ["more", "values"]], {"nested": ["deepest", "second"]}, ["deepest", "second"], "deepest", "second", ["more", "values"], "more", "values"]`); !reflect.DeepEqual(nested, expectation) {
+		t.Errorf("expect %v, got %v", expectation, nested)
+	}
+	if leaves := get(`$[0]..`); !reflect.DeepEqual(leaves, []interface{}{"first"}) {
+		t.Errorf("expect a scalar itself, got %v", leaves)
+	}
+}
+
+func TestGetExcluding(t *testing.T) {
+	for _, filter := range []string{`@.price > 10`, `?(@.price > 10)`, `[?(@.price>10)]`} {
+		j, err := New("books", `$.store.book`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.InitData(ConvertToJsonObj(bookstoreData))
+		result, err := j.GetExcluding(filter)
+		if err != nil {
+			t.Fatalf("%s: %v", filter, err)
+		}
+		titles := make([]interface{}, len(result))
+		for i, r := range result {
+			titles[i] = (*r.(*interface{})).(map[string]interface{})["title"]
+		}
+		if expectation := []interface{}{"Sayings of the Century", "Moby Dick"}; !reflect.DeepEqual(titles, expectation) {
+			t.Errorf("%s: expect %v, got %v", filter, expectation, titles)
+		}
+	}
+
+	j, err := New("prices", `$..book[*].price`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(ConvertToJsonObj(bookstoreData))
+	if result, err := j.GetExcluding(`@ > 10`); err != nil || len(result) != 0 {
+		t.Errorf("expect no elements of scalars, got %v, %v", result, err)
+	}
+	if _, err := j.GetExcluding(`@.a ==`); err == nil {
+		t.Errorf("expect an error for an invalid filter")
+	}
+}
+
+func TestSetSafe(t *testing.T) {
+	cases := []struct {
+		expr string
+		data string
+		init func(j *Jsonpath)
+	}{
+		{`$.a[0].b`, `{"a": 5}`, nil},
+		{`$.a[5]`, `{"a": [1]}`, func(j *Jsonpath) { j.SetStrict(true) }},
+		{`$..*`, `{"a": [1, 2, 3]}`, func(j *Jsonpath) { j.SetMaxResults(2) }},
+		{`$[?(size(@) > 2)]`, `[[1, 2, 3]]`, nil},
+		{`$[?(@.id == $$.id)]`, `[{"id": 1}]`, nil},
+		{`$[?(@.a > 1)]`, `[{"a": "x"}]`, nil},
+	}
+	for _, c := range cases {
+		for _, safe := range []bool{false, true} {
+			j, err := New(c.expr, c.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.init != nil {
+				c.init(j)
+			}
+			j.SetSafe(safe)
+			j.InitData(ConvertToJsonObj(c.data))
+			result, err := j.Get()
+			if !safe {
+				if err == nil && len(j.warnings) == 0 {
+					t.Errorf("%s over %s: expect an error or a warning", c.expr, c.data)
+				}
+				continue
+			}
+			if err != nil || len(result) != 0 {
+				t.Errorf("%s over %s: expect no results, got %v, %v", c.expr, c.data, result, err)
+			}
+			if len(j.warnings) == 0 {
+				t.Errorf("%s over %s: expect a warning", c.expr, c.data)
+			}
+			if _, err := j.Exists(); err != nil {
+				t.Errorf("%s over %s: expect Exists not to fail, got %v", c.expr, c.data, err)
+			}
+			results, errs := j.Stream()
+			for range results {
+			}
+			if err := <-errs; err != nil {
+				t.Errorf("%s over %s: expect Stream not to fail, got %v", c.expr, c.data, err)
+			}
+			results, errs = j.StreamContext(context.Background())
+			for range results {
+			}
+			if err := <-errs; err != nil {
+				t.Errorf("%s over %s: expect StreamContext not to fail, got %v", c.expr, c.data, err)
+			}
+		}
+	}
+
+	j, err := New("safe", `$..*`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.SetSafe(true)
+	j.InitData(ConvertToJsonObj(`{"a": [1, 2, 3]}`))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := j.GetContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expect the error of the context, got %v", err)
+	}
+
+	j, err = New("safe", `$[-5]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.SetSafe(true)
+	j.InitData(ConvertToJsonObj(`[1, 2, 3]`))
+	if err := j.Set(0); err == nil {
+		t.Errorf("expect Set of %s to fail", `$[-5]`)
+	}
+}
+
+func TestGetMulti(t *testing.T) {
+	result, err := GetMulti(ConvertToJsonObj(bookstoreData), `$.store.book[*].title`, `$.store.book[*].price`, `$.store.bicycle.price`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectations := []string{
+		`["Sayings of the Century", "Sword of Honour", "Moby Dick", "The Lord of the Rings"]`,
+		`[8.95, 12.99, 8.99, 22.99]`,
+		`[19.95]`,
+	}
+	if len(result) != len(expectations) {
+		t.Fatalf("expect %d lists, got %d", len(expectations), len(result))
+	}
+	for i, list := range result {
+		got := resultValues(list)
+		if !reflect.DeepEqual(got, ConvertToJsonObj(expectations[i])) {
+			t.Errorf("list %d: expect %s, got %v", i, expectations[i], got)
+		}
+	}
+
+	data := ConvertToJsonObj(`{"a": {"b": 1}}`)
+	if result, err := GetMulti(data, `$.a`, `$.a.b`); err != nil || len(result) != 2 || len(result[1]) != 1 {
+		t.Errorf("expect a value of each path, got %v, %v", result, err)
+	}
+	if result, err := GetMulti(data); err != nil || len(result) != 0 {
+		t.Errorf("expect no lists without expressions, got %v, %v", result, err)
+	}
+	if _, err := GetMulti(data, `$.a`, `$.a[`); err == nil {
+		t.Errorf("expect an error for an invalid expression")
+	}
+	if _, err := GetMulti(data, `$.a`, `$[?(size(@) > 1)]`); err == nil {
+		t.Errorf("expect an error for an unknown function")
+	}
+}
+
+func TestGetThen(t *testing.T) {
+	j, err := New("store", `$.store`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(ConvertToJsonObj(bookstoreData))
+	result, err := j.GetThen(`$.book[*].price`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := resultValues(result)
+	if expectation := []interface{}{8.95, 12.99, 8.99, 22.99}; !reflect.DeepEqual(values, expectation) {
+		t.Errorf("expect %v, got %v", expectation, values)
+	}
+
+	j, _ = New("books", `$.store.book[?(@.isbn)]`)
+	j.InitData(ConvertToJsonObj(bookstoreData))
+	result, err = j.GetThen(`$.title`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 2 {
+		t.Errorf("expect the titles of 2 books, got %v", result)
+	}
+
+	if _, err := j.GetThen(`$[`); err == nil {
+		t.Errorf("expect an error for an invalid sub path")
+	}
+}
+
+func TestSecondaryRoot(t *testing.T) {
+	j, err := New("join", `$[?(@.id == $$.targetId)].name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(ConvertToJsonObj(`[{"id": 1, "name": "a"}, {"id": 2, "name": "b"}]`))
+	if _, err := j.Get(); err == nil {
+		t.Errorf("expect an error without a secondary document")
+	}
+
+	j.InitSecondaryData(ConvertToJsonObj(`{"targetId": 2}`))
+	value, err := j.GetSingle()
+	if err != nil || value != "b" {
+		t.Errorf("expect b, got %v, %v", value, err)
+	}
+
+	j, _ = New("secondary", `$$.targetId`)
+	j.InitData(ConvertToJsonObj(`{"targetId": 1}`))
+	j.InitSecondaryData(ConvertToJsonObj(`{"targetId": 2}`))
+	if value, err := j.GetSingle(); err != nil || value != 2.0 {
+		t.Errorf("expect 2, got %v, %v", value, err)
+	}
+}
+
+func TestBetweenReversedBoundsWarns(t *testing.T) {
+	j, err := New("between", `$[?(@ between 3 and 1)]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(ConvertToJsonObj(`[2]`))
+	result, err := j.Get()
+	if err != nil || len(result) != 0 {
+		t.Errorf("expect no result, got %v, %v", result, err)
+	}
+	if expectation := []string{"the lower bound 3 of between is greater than the upper bound 1"}; !reflect.DeepEqual(j.warnings, expectation) {
+		t.Errorf("expect the warnings %v, got %v", expectation, j.warnings)
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	j, err := New("books", `$.store.book[*]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(ConvertToJsonObj(bookstoreData))
+	groups, err := j.GroupBy(`@.category`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles := make(map[interface{}][]interface{})
+	for key, books := range groups {
+		for _, book := range books {
+			titles[key] = append(titles[key], book.(map[string]interface{})["title"])
+		}
+	}
+	expectation := map[interface{}][]interface{}{
+		"reference": {"Sayings of the Century"},
+		"fiction":   {"Sword of Honour", "Moby Dick", "The Lord of the Rings"},
+	}
+	if !reflect.DeepEqual(titles, expectation) {
+		t.Errorf("expect %v, got %v", expectation, titles)
+	}
+
+	j, _ = New("items", `$[*]`)
+	j.InitData(ConvertToJsonObj(`[{"k": 1}, {"k": [1]}, {}, {"k": 1}, {"k": true}, {"k": "1"}, {"k": ""}, {"k": null}]`))
+	groups, err = j.GroupBy(`k`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sizes := make(map[interface{}]int)
+	for key, values := range groups {
+		sizes[key] = len(values)
+	}
+	if expectation := map[interface{}]int{1.0: 2, "1": 1, "": 1, true: 1, Ungrouped: 3}; !reflect.DeepEqual(sizes, expectation) {
+		t.Errorf("expect the groups %v, got %v", expectation, sizes)
+	}
+	if len(j.warnings) != 3 {
+		t.Errorf("expect a warning for each match without a scalar key, got %v", j.warnings)
+	}
+}
+
+func TestProject(t *testing.T) {
+	j, err := New("books", `$.store.book[*]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(ConvertToJsonObj(bookstoreData))
+	rows, err := j.Project(map[string]string{"title": "@.title", "price": "price", "isbn": "@.isbn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectation := ConvertToJsonObj(`[
+		{"title": "Sayings of the Century", "price": 8.95, "isbn": null},
+		{"title": "Sword of Honour", "price": 12.99, "isbn": null},
+		{"title": "Moby Dick", "price": 8.99, "isbn": "0-553-21311-3"},
+		{"title": "The Lord of the Rings", "price": 22.99, "isbn": "0-395-19395-8"}
+	]`).([]interface{})
+	if len(rows) != len(expectation) {
+		t.Fatalf("expect %d rows, got %v", len(expectation), rows)
+	}
+	for i, row := range rows {
+		if !reflect.DeepEqual(row, expectation[i]) {
+			t.Errorf("row %d: expect %v, got %v", i, expectation[i], row)
+		}
+	}
+
+	j, err = New("store", `$.store`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(ConvertToJsonObj(bookstoreData))
+	rows, err = j.Project(map[string]string{"categories": "book[*].category"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expectation := ConvertToJsonObj(`["reference", "fiction", "fiction", "fiction"]`); len(rows) != 1 || !reflect.DeepEqual(rows[0]["categories"], expectation) {
+		t.Errorf("expect the cell of all the categories, got %v", rows)
+	}
+	if _, err := j.Project(map[string]string{"broken": "@.a[?("}); err == nil {
+		t.Errorf("expect an error for an invalid column")
+	}
+}
+
+func TestGetRoot(t *testing.T) {
+	cases := []struct {
+		expr string
+		data string
+	}{
+		{`$`, `{"a": 1}`},
+		{`@`, `{"a": 1}`},
+		{` $ `, `[1, 2]`},
+		{`$`, `"s"`},
+	}
+	for _, c := range cases {
+		j, err := New(c.expr, c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := ConvertToJsonObj(c.data)
+		j.InitData(data)
+		result, err := j.Get()
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		if len(result) != 1 || !reflect.DeepEqual(*result[0].(*interface{}), data) {
+			t.Errorf("%s: expect [%s], got %v", c.expr, c.data, result)
+		}
+	}
+
+	j, _ := New("empty", ``)
+	j.InitData(ConvertToJsonObj(`{}`))
+	if _, err := j.Get(); err == nil {
+		t.Errorf("expect an error for an empty expression")
+	}
+
+	j, _ = New("set", `$`)
+	j.InitData(ConvertToJsonObj(`{}`))
+	if err := j.Set("x"); err != nil || j.Data() != "x" {
+		t.Errorf("expect the whole data to be set, got %v, %v", j.Data(), err)
+	}
+}
+
+func TestRFCModeRejects(t *testing.T) {
+	for _, expr := range []string{
+		`$...u`,
+		`$.a.`,
+		`$.a[?(@ === 3)]`,
+		`$.a[?(@ in [1, 2])]`,
+		`$.a[?(@ between 1 and 3)]`,
+		`$.a[?(@.b =~ /k.*/)]`,
+		`$.a.first(2)`,
+		`$.a[[0, 1]]`,
+		`$$.a`,
+		`$.u*`,
+		`$.a.nth(2)`,
+		`$.a[?(all @[*] > 1)]`,
+		`$[?(@.a ^= "x")]`,
+		`$..price^.title`,
+	} {
+		j, err := New("rfc", expr)
+		if err != nil {
+			t.Errorf("%s: expect the path to be accepted by default, got %v", expr, err)
+			continue
+		}
+		j.InitData(ConvertToJsonObj(`{"a": [1, 2, 3], "u": 1}`))
+		j.InitSecondaryData(ConvertToJsonObj(`{"a": 1}`))
+		if _, err := j.Get(); err != nil {
+			t.Errorf("%s: expect the path to be accepted by default, got %v", expr, err)
+		}
+		j.SetRFCMode(true)
+		if _, err := j.Get(); err == nil || !strings.Contains(err.Error(), "RFC mode") {
+			t.Errorf("%s: expect an RFC mode error, got %v", expr, err)
+		}
+	}
+}
+
+func TestGetInto(t *testing.T) {
+	j, err := New("prices", `$.store.book[*].price`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(ConvertToJsonObj(bookstoreData))
+	var prices []float64
+	if err := j.GetInto(&prices); err != nil {
+		t.Fatal(err)
+	}
+	if expectation := []float64{8.95, 12.99, 8.99, 22.99}; !reflect.DeepEqual(prices, expectation) {
+		t.Errorf("expect %v, got %v", expectation, prices)
+	}
+	var price float64
+	if err := j.GetInto(&price); err == nil {
+		t.Errorf("expect an error getting several results into a scalar")
+	}
+	var titles []string
+	if err := j.GetInto(&titles); err == nil {
+		t.Errorf("expect an error getting numbers into strings")
+	}
+
+	j, err = New("bicycle", `$.store.bicycle.price`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(ConvertToJsonObj(bookstoreData))
+	if err := j.GetInto(&price); err != nil {
+		t.Fatal(err)
+	}
+	if price != 19.95 {
+		t.Errorf("expect 19.95, got %v", price)
+	}
+	if err := j.GetInto(price); err == nil {
+		t.Errorf("expect an error getting into a non-pointer")
+	}
+}
+
+func TestInitDataInterfaceMap(t *testing.T) {
+	data := map[interface{}]interface{}{
+		"key": "value",
+		"nested": []interface{}{
+			map[interface{}]interface{}{1: "one", true: "yes"},
+		},
+		"plain": map[string]interface{}{
+			"inner": map[interface{}]interface{}{"a": 1.0},
+		},
+	}
+	cases := []struct {
+		expr        string
+		expectation []interface{}
+	}{
+		{`$.key`, []interface{}{"value"}},
+		{`$.nested[0]['1']`, []interface{}{"one"}},
+		{`$.nested[0].true`, []interface{}{"yes"}},
+		{`$.plain.inner.a`, []interface{}{1.0}},
+	}
+	for _, c := range cases {
+		j, err := New("interface map", c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.InitData(data)
+		result, err := j.Get()
+		if err != nil {
+			t.Fatalf("%s: %v", c.expr, err)
+		}
+		values := resultValues(result)
+		if !reflect.DeepEqual(values, c.expectation) {
+			t.Errorf("%s: expect %v, got %v", c.expr, c.expectation, values)
+		}
+	}
+
+	j, err := New("interface map", `$.*`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(map[interface{}]interface{}{1: "a", "1": "b", [2]int{}: "c"})
+	if len(j.warnings) != 2 {
+		t.Errorf("expect a warning for the clashing key and one for the array key, got %v", j.warnings)
 	}
-	t.Logf("SUMMARY: [TOTAL]=%d [✅PASS]=%d [⛔️FAIL]=%d", caseCount, caseCount-failCount, failCount)
 }
@@ -2,6 +2,8 @@ package jsonpath
 
 import (
 	"encoding/json"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -10,6 +12,7 @@ type JsonpathSetCase struct {
 	expr        string
 	data        string
 	change      interface{}
+	expectation string
 	isErrorCase bool
 }
 
@@ -57,6 +60,125 @@ func SetCases() []JsonpathSetCase {
 			data:   `[]`,
 			change: false,
 		},
+		{
+			name:        "field of every element selected by wildcard",
+			expr:        "$.items[*].processed",
+			data:        `{"items":[{"id":1},{"id":2,"processed":false}]}`,
+			change:      true,
+			expectation: `{"items":[{"id":1,"processed":true},{"id":2,"processed":true}]}`,
+		},
+		{
+			name:        "field of every element selected by bracket wildcard",
+			expr:        "$[*].field",
+			data:        `[{},{"field":0}]`,
+			change:      "x",
+			expectation: `[{"field":"x"},{"field":"x"}]`,
+		},
+		{
+			name:        "field of elements selected by filter",
+			expr:        "$[?(@.x)].field",
+			data:        `[{"x":1},{"y":2},{"x":3,"field":0}]`,
+			change:      true,
+			expectation: `[{"x":1,"field":true},{"y":2},{"x":3,"field":true}]`,
+		},
+		{
+			name:        "field of elements selected by comparison filter",
+			expr:        "$[?(@.x>1)].field",
+			data:        `[{"x":1},{"y":2},{"x":3}]`,
+			change:      true,
+			expectation: `[{"x":1},{"y":2},{"x":3,"field":true}]`,
+		},
+		{
+			name:        "slice in missing field",
+			expr:        "$.a[0:2]",
+			data:        `{}`,
+			change:      nil,
+			expectation: `{"a":[null,null]}`,
+		},
+		{
+			name:        "slice with start in missing nested field",
+			expr:        "$.a.b[1:3]",
+			data:        `{"a":{}}`,
+			change:      true,
+			expectation: `{"a":{"b":[null,true,true]}}`,
+		},
+		{
+			name:        "index list beyond a short array",
+			expr:        "$[[3,0]]",
+			data:        `[1,2]`,
+			change:      0.0,
+			expectation: `[0,2,null,0]`,
+		},
+		{
+			name:        "open-end slice beyond a short array",
+			expr:        "$[3:]",
+			data:        `[1,2]`,
+			change:      0.0,
+			expectation: `[1,2,null,0]`,
+		},
+		{
+			name:        "open-end slice inside an array",
+			expr:        "$[1:]",
+			data:        `[1,2,3]`,
+			change:      0.0,
+			expectation: `[1,0,0]`,
+		},
+		{
+			name:        "slice beyond a short array",
+			expr:        "$[3:5]",
+			data:        `[1,2]`,
+			change:      0.0,
+			expectation: `[1,2,null,0,0]`,
+		},
+		{
+			name:        "slice with negative start never grows",
+			expr:        "$[-5:]",
+			data:        `[1,2]`,
+			change:      0.0,
+			expectation: `[0,0]`,
+		},
+		{
+			name:        "negative index in array",
+			expr:        "$[-1]",
+			data:        `[1,2,3]`,
+			change:      0.0,
+			expectation: `[1,2,0]`,
+		},
+		{
+			name:        "negative index of every array",
+			expr:        "$[*][-2]",
+			data:        `[[1,2],[3,4,5]]`,
+			change:      0.0,
+			expectation: `[[0,2],[3,0,5]]`,
+		},
+		{
+			name:        "negative index out of range",
+			expr:        "$[-5]",
+			data:        `[1,2,3]`,
+			change:      0,
+			isErrorCase: true,
+		},
+		{
+			name:        "negative index in missing array",
+			expr:        "$.a[-1]",
+			data:        `{}`,
+			change:      0,
+			isErrorCase: true,
+		},
+		{
+			name:        "field of scalar selected by wildcard",
+			expr:        "$[*].field",
+			data:        `[1,{"y":2}]`,
+			change:      true,
+			isErrorCase: true,
+		},
+		{
+			name:        "sibling through parent",
+			expr:        "$.a.b^.c",
+			data:        `{"a":{"b":1}}`,
+			change:      2,
+			isErrorCase: true,
+		},
 	}
 }
 
@@ -71,8 +193,17 @@ func TestSetFunction(t *testing.T) {
 		jsonObj := ConvertToJsonObj(c.data)
 		j.InitData(jsonObj)
 		err = j.Set(c.change)
-		if err != nil {
+		if c.isErrorCase {
+			if err == nil {
+				t.Errorf("%s: expect an error", c.name)
+			} else {
+				t.Logf("expected error: %s", err)
+			}
+		} else if err != nil {
 			t.Errorf(err.Error())
+		} else if c.expectation != "" && !Equal(j.Data(), ConvertToJsonObj(c.expectation)) {
+			marshal, _ := json.Marshal(j.Data())
+			t.Errorf("%s: the result %s, the expectation %s", c.name, marshal, c.expectation)
 		} else {
 			marshal, err := json.Marshal(j.Data())
 			if err != nil {
@@ -82,3 +213,300 @@ func TestSetFunction(t *testing.T) {
 		}
 	}
 }
+
+type JsonpathMergeCase struct {
+	name        string
+	expr        string
+	data        string
+	partial     string
+	expectation string
+	isErrorCase bool
+}
+
+func MergeCases() []JsonpathMergeCase {
+	return []JsonpathMergeCase{
+		{
+			name:        "merge into object",
+			expr:        "$.config",
+			data:        `{"config":{"a":1}}`,
+			partial:     `{"b":2}`,
+			expectation: `{"config":{"a":1,"b":2}}`,
+		},
+		{
+			name:        "merge nested objects and replace arrays",
+			expr:        "$.config",
+			data:        `{"config":{"a":{"x":1,"y":2},"list":[1,2,3],"s":"old"}}`,
+			partial:     `{"a":{"y":3,"z":4},"list":[4],"s":"new"}`,
+			expectation: `{"config":{"a":{"x":1,"y":3,"z":4},"list":[4],"s":"new"}}`,
+		},
+		{
+			name:        "merge into missing object",
+			expr:        "$.config",
+			data:        `{}`,
+			partial:     `{"b":2}`,
+			expectation: `{"config":{"b":2}}`,
+		},
+		{
+			name:        "merge into every matched object",
+			expr:        "$[*]",
+			data:        `[{"a":1},{"a":2}]`,
+			partial:     `{"b":0}`,
+			expectation: `[{"a":1,"b":0},{"a":2,"b":0}]`,
+		},
+		{
+			name:        "merge into scalar",
+			expr:        "$.config",
+			data:        `{"config":1}`,
+			partial:     `{"b":2}`,
+			isErrorCase: true,
+		},
+	}
+}
+
+func TestMergeFunction(t *testing.T) {
+	for _, c := range MergeCases() {
+		j, err := New(c.name, c.expr)
+		if err != nil {
+			t.Fatalf("cannot parse jsonpath")
+		}
+		j.InitData(ConvertToJsonObj(c.data))
+		err = j.Merge(ConvertToJsonObj(c.partial).(map[string]interface{}))
+		if c.isErrorCase {
+			if err == nil {
+				t.Errorf("%s: expect an error", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if !Equal(j.Data(), ConvertToJsonObj(c.expectation)) {
+			marshal, _ := json.Marshal(j.Data())
+			t.Errorf("%s: the result %s, the expectation %s", c.name, marshal, c.expectation)
+		}
+	}
+}
+
+func TestMergeCopiesPartial(t *testing.T) {
+	j, err := New("merge", `$.items[*]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(ConvertToJsonObj(`{"items":[{"id":1},{"id":2}]}`))
+	partial := ConvertToJsonObj(`{"meta":{"v":1},"tags":["a"]}`).(map[string]interface{})
+	if err := j.Merge(partial); err != nil {
+		t.Fatal(err)
+	}
+	data := j.Data()
+
+	j, err = New("set", `$.items[0].meta.v`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(data)
+	if err := j.Set(2.0); err != nil {
+		t.Fatal(err)
+	}
+	expectation := `{"items":[{"id":1,"meta":{"v":2},"tags":["a"]},{"id":2,"meta":{"v":1},"tags":["a"]}]}`
+	if !Equal(j.Data(), ConvertToJsonObj(expectation)) {
+		marshal, _ := json.Marshal(j.Data())
+		t.Errorf("the result %s, the expectation %s", marshal, expectation)
+	}
+	if !Equal(partial, ConvertToJsonObj(`{"meta":{"v":1},"tags":["a"]}`)) {
+		t.Errorf("the partial is modified to %v", partial)
+	}
+}
+
+func TestSetPreview(t *testing.T) {
+	cases := []struct {
+		expr        string
+		data        string
+		expectation []string
+	}{
+		{"$.a.b.c", `{}`, []string{"$['a']['b']['c']"}},
+		{"$[1:3]", `[]`, []string{"$[1]", "$[2]"}},
+		{"$.items[*].done", `{"items":[{},{"done":false}]}`, []string{"$['items'][0]['done']", "$['items'][1]['done']"}},
+		{"$[?(@.x)].y", `[{"x":1},{"z":1}]`, []string{"$[0]['y']"}},
+		{"$.a[2].b.c", `{"a":[{"b":{}}]}`, []string{"$['a'][2]['b']['c']"}},
+	}
+	for _, c := range cases {
+		j, err := New(c.expr, c.expr)
+		if err != nil {
+			t.Fatalf("cannot parse jsonpath")
+		}
+		j.InitData(ConvertToJsonObj(c.data))
+		paths, err := j.SetPreview()
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(paths, c.expectation) {
+			t.Errorf("%s: the paths %v, the expectation %v", c.expr, paths, c.expectation)
+		}
+		if !Equal(j.Data(), ConvertToJsonObj(c.data)) {
+			marshal, _ := json.Marshal(j.Data())
+			t.Errorf("%s: the data is modified to %s", c.expr, marshal)
+		}
+	}
+}
+
+func TestApplyEdits(t *testing.T) {
+	source := `{"name":"app","replicas":1,"ports":[80]}`
+	data := ConvertToJsonObj(source)
+	edited, err := ApplyEdits(data, map[string]interface{}{
+		"$.replicas":       3,
+		"$.ports[1]":       443,
+		"$.labels.tier":    "web",
+		"$.labels.version": "v2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectation := `{"name":"app","replicas":3,"ports":[80,443],"labels":{"tier":"web","version":"v2"}}`
+	marshal, _ := json.Marshal(edited)
+	var result, expected interface{}
+	json.Unmarshal(marshal, &result)
+	json.Unmarshal([]byte(expectation), &expected)
+	if !Equal(result, expected) {
+		t.Errorf("the result %s, the expectation %s", marshal, expectation)
+	}
+	if !Equal(data, ConvertToJsonObj(source)) {
+		marshal, _ := json.Marshal(data)
+		t.Errorf("the data is modified to %s", marshal)
+	}
+}
+
+func TestApplyEditsGrowsRoot(t *testing.T) {
+	data := []interface{}{1.0}
+	edited, err := ApplyEdits(data, map[string]interface{}{"$[3]": 2.0, "$[1]": 3.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expectation := []interface{}{1.0, 3.0, nil, 2.0}; !reflect.DeepEqual(edited, expectation) {
+		t.Errorf("the result %v, the expectation %v", edited, expectation)
+	}
+	if !reflect.DeepEqual(data, []interface{}{1.0}) {
+		t.Errorf("the data is modified to %v", data)
+	}
+}
+
+func TestApplyEditsIsAtomic(t *testing.T) {
+	source := `{"name":"app","ports":[80]}`
+	data := ConvertToJsonObj(source)
+	_, err := ApplyEdits(data, map[string]interface{}{
+		"$.a":         1,
+		"$.name.port": 8080,
+		"$.ports[2]":  443,
+	})
+	if err == nil || !strings.Contains(err.Error(), "$.name.port") {
+		t.Fatalf("expect the failing edit to be reported, got %v", err)
+	}
+	if !Equal(data, ConvertToJsonObj(source)) {
+		marshal, _ := json.Marshal(data)
+		t.Errorf("the data is modified to %s", marshal)
+	}
+
+	if _, err := ApplyEdits(data, map[string]interface{}{"$[?(": 1}); err == nil {
+		t.Errorf("expect an error for an invalid jsonpath")
+	}
+}
+
+func TestPlan(t *testing.T) {
+	cases := []struct {
+		expr        string
+		data        string
+		expectation string
+	}{
+		{"$.a.b.c", `{}`, `{"a":{"b":{"c":null}}}`},
+		{"$.a.b.c", `{"a":{"x":1}}`, `{"a":{"x":1,"b":{"c":null}}}`},
+		{"$.a[1]", `{}`, `{"a":[null,null]}`},
+		{"$.items[*].done", `{"items":[{},{"done":false}]}`, `{"items":[{"done":null},{"done":false}]}`},
+	}
+	for _, c := range cases {
+		j, err := New(c.expr, c.expr)
+		if err != nil {
+			t.Fatalf("cannot parse jsonpath")
+		}
+		j.InitData(ConvertToJsonObj(c.data))
+		plan, err := j.Plan()
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		if !Equal(plan, ConvertToJsonObj(c.expectation)) {
+			marshal, _ := json.Marshal(plan)
+			t.Errorf("%s: the plan %s, the expectation %s", c.expr, marshal, c.expectation)
+		}
+		if !Equal(j.Data(), ConvertToJsonObj(c.data)) {
+			marshal, _ := json.Marshal(j.Data())
+			t.Errorf("%s: the data is modified to %s", c.expr, marshal)
+		}
+	}
+}
+
+func TestSetIfAbsent(t *testing.T) {
+	cases := []struct {
+		expr        string
+		data        string
+		expectation string
+	}{
+		{"$.a.b", `{"a":{"b":5}}`, `{"a":{"b":5}}`},
+		{"$.a.b", `{}`, `{"a":{"b":"default"}}`},
+		{"$.a.b", `{"a":{"b":null}}`, `{"a":{"b":"default"}}`},
+		{"$.a.b", `{"a":{"b":false}}`, `{"a":{"b":false}}`},
+		{"$.items[*].name", `{"items":[{"name":"x"},{},{"name":null}]}`, `{"items":[{"name":"x"},{"name":"default"},{"name":"default"}]}`},
+		{"$.list[2]", `{"list":[1]}`, `{"list":[1,null,"default"]}`},
+	}
+	for _, c := range cases {
+		j, err := New(c.expr, c.expr)
+		if err != nil {
+			t.Fatalf("cannot parse jsonpath")
+		}
+		j.InitData(ConvertToJsonObj(c.data))
+		if err := j.SetIfAbsent("default"); err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		if !Equal(j.Data(), ConvertToJsonObj(c.expectation)) {
+			marshal, _ := json.Marshal(j.Data())
+			t.Errorf("%s over %s: the result %s, the expectation %s", c.expr, c.data, marshal, c.expectation)
+		}
+	}
+}
+
+func TestSetMaxGrow(t *testing.T) {
+	cases := []struct {
+		expr        string
+		data        string
+		expectation string
+		err         string
+	}{
+		{`$[3]`, `[1,2,3]`, `[1,2,3,0]`, ""},
+		{`$[5]`, `[1,2,3]`, `[1,2,3,null,null,0]`, ""},
+		{`$[6]`, `[1,2,3]`, "", "cannot grow the array at $ by 4 elements, the limit is 3"},
+		{`$.a[10]`, `{"a":[]}`, "", "cannot grow the array at $['a'] by 11 elements, the limit is 3"},
+		{`$.a[1:4]`, `{}`, "", "cannot grow the array at $['a'] by 4 elements, the limit is 3"},
+		{`$.a[[0,2]]`, `{}`, `{"a":[0,null,0]}`, ""},
+	}
+	for _, c := range cases {
+		j, err := New(c.expr, c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.SetMaxGrow(3)
+		j.InitData(ConvertToJsonObj(c.data))
+		err = j.Set(0.0)
+		if c.err != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), c.err) {
+				t.Errorf("%s: expect the error %q, got %v", c.expr, c.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+		} else if !Equal(j.Data(), ConvertToJsonObj(c.expectation)) {
+			t.Errorf("%s: expect %s, got %v", c.expr, c.expectation, j.Data())
+		}
+	}
+}
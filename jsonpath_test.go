@@ -0,0 +1,384 @@
+package jsonpath
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCloneEvaluatesConcurrently(t *testing.T) {
+	j, err := New("clone", `$.items[0:2].missing`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clones := make([]*Jsonpath, 8)
+	for i := range clones {
+		clones[i] = j.Clone()
+		clones[i].InitData(ConvertToJsonObj(fmt.Sprintf(`{"items":[{"missing":%d},{"other":%d}]}`, i, i)))
+	}
+
+	wg := sync.WaitGroup{}
+	results := make([][]interface{}, len(clones))
+	errs := make([]error, len(clones))
+	for i := range clones {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = clones[i].Get()
+		}(i)
+	}
+	wg.Wait()
+
+	for i, c := range clones {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if len(results[i]) != 1 || *results[i][0].(*interface{}) != float64(i) {
+			t.Errorf("clone %d: unexpected result %v", i, results[i])
+		}
+		if len(c.warnings) != 1 {
+			t.Errorf("clone %d: expect one warning of its own, got %v", i, c.warnings)
+		}
+	}
+	if len(j.warnings) != 0 || len(j.dataHolder) != 0 {
+		t.Errorf("the original jsonpath is modified by its clones")
+	}
+}
+
+func TestCloneSetDoesNotChangeSharedExpression(t *testing.T) {
+	j, err := New("clone", `$[:2]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := j.Clone()
+	c.InitData(ConvertToJsonObj(`[]`))
+	if err := c.Set(0); err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(ConvertToJsonObj(`[1, 2, 3]`))
+	result, err := j.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 2 {
+		t.Errorf("unexpected result %v", result)
+	}
+}
+
+func TestNewRelaxed(t *testing.T) {
+	cases := []struct {
+		expr        string
+		data        string
+		expectation interface{}
+	}{
+		{"key", `{"key":"value"}`, "value"},
+		{".key", `{"key":"value"}`, "value"},
+		{"$.key", `{"key":"value"}`, "value"},
+		{"['key']", `{"key":"value"}`, "value"},
+		{"store.book[0].price", bookstoreData, 8.95},
+	}
+	for _, c := range cases {
+		j, err := NewRelaxed(c.expr, c.expr)
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		j.InitData(ConvertToJsonObj(c.data))
+		result, err := j.Get()
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		if len(result) != 1 || *result[0].(*interface{}) != c.expectation {
+			t.Errorf("%s: unexpected result %v", c.expr, result)
+		}
+	}
+
+	j, err := New("strict", "key")
+	if err == nil {
+		j.InitData(ConvertToJsonObj(`{"key":"value"}`))
+		_, err = j.Get()
+	}
+	if err == nil {
+		t.Errorf("expect an error for a path without root in strict mode")
+	}
+}
+
+func TestSetMaxResults(t *testing.T) {
+	items := make([]string, 0)
+	for i := 0; i < 100; i++ {
+		items = append(items, fmt.Sprintf(`{"id":%d,"tags":["a","b"]}`, i))
+	}
+	data := ConvertToJsonObj("[" + strings.Join(items, ",") + "]")
+
+	cases := []struct {
+		expr        string
+		maxResults  int
+		isErrorCase bool
+	}{
+		{`$..*`, 10, true},
+		{`$..*`, 0, false},
+		{`$..*`, 1000, false},
+		{`$[*].tags[*]`, 150, true},
+		{`$[*].tags[*]`, 200, false},
+		{`$[0:5].id`, 1, false},
+	}
+	for _, c := range cases {
+		j, err := New(c.expr, c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.SetMaxResults(c.maxResults)
+		j.InitData(data)
+		_, err = j.Get()
+		if c.isErrorCase && err == nil {
+			t.Errorf("%s: expect an error with the limit %d", c.expr, c.maxResults)
+		} else if !c.isErrorCase && err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+		}
+	}
+}
+
+func TestGetContext(t *testing.T) {
+	var data interface{} = "leaf"
+	for i := 0; i < 200; i++ {
+		data = map[string]interface{}{"child": data, "sibling": []interface{}{i}}
+	}
+
+	j, err := New("context", `$..sibling`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(data)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := j.GetContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expect %v, got %v", context.Canceled, err)
+	}
+
+	result, err := j.GetContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 200 {
+		t.Errorf("expect 200 results, got %d", len(result))
+	}
+}
+
+func TestEvalErrorReportsSegment(t *testing.T) {
+	cases := []struct {
+		expr        string
+		expectation string
+	}{
+		{`$.items[?(@.tags[*] == 1)].x`, "at segment [?(@.tags[*] == 1)] (char 7)"},
+		{`$..a[?(size(@) > 1)]`, "at segment [?(size(@) > 1)] (char 4)"},
+		{`$['items'][0][?(@[*] > 1)]`, "at segment [?(@[*] > 1)] (char 13)"},
+	}
+	for _, c := range cases {
+		j, err := New("segment", c.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.InitData(ConvertToJsonObj(`{"items":[[[1,2]],{"tags":[1,2]}],"a":[{"x":1}]}`))
+		_, err = j.Get()
+		if err == nil || !strings.HasSuffix(err.Error(), c.expectation) {
+			t.Errorf("%s: expect the error to end with %q, got %v", c.expr, c.expectation, err)
+		}
+	}
+}
+
+func TestNodePosition(t *testing.T) {
+	j, err := New("position", `$.store..book[0:2]['title', 'price']`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	positions := make([]Pos, 0)
+	for _, node := range j.AST().(*ListNode).Nodes {
+		positions = append(positions, node.Position())
+	}
+	expectation := []Pos{1, 7, 9, 13, 18}
+	if fmt.Sprint(positions) != fmt.Sprint(expectation) {
+		t.Errorf("the positions %v, the expectation %v", positions, expectation)
+	}
+}
+
+func TestEmptyUnionMemberError(t *testing.T) {
+	cases := map[string]string{
+		`$['a',]`:     "empty union member 1 in ['a',]",
+		`$[,'a']`:     "empty union member 0 in [,'a']",
+		`$['a',,'b']`: "empty union member 1 in ['a',,'b']",
+		`$[0, 1,  ]`:  "empty union member 2 in [0, 1,]",
+	}
+	for expr, expectation := range cases {
+		_, err := New(expr, expr)
+		if err == nil || !strings.HasSuffix(err.Error(), expectation) {
+			t.Errorf("%s: expect the error %q, got %v", expr, expectation, err)
+		}
+	}
+}
+
+func TestRegisterFunc(t *testing.T) {
+	data := ConvertToJsonObj(`[{"name": "bob", "x": 2}, {"name": "alice", "x": 5}]`)
+	upper := func(args ...interface{}) (interface{}, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("expect a string, got %v", args[0])
+		}
+		return strings.ToUpper(s), nil
+	}
+	double := func(args ...interface{}) (interface{}, error) {
+		return args[0].(float64) * 2, nil
+	}
+
+	j, err := New("upper", `$[?(upper(@.name) == "BOB")].x`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.RegisterFunc("upper", upper)
+	j.InitData(data)
+	result, err := j.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 || *result[0].(*interface{}) != 2.0 {
+		t.Errorf("expect [2], got %v", result)
+	}
+
+	j, _ = New("double", `$[?(double(@.x) > 6)].name`)
+	j.RegisterFunc("double", double)
+	j.InitData(data)
+	result, err = j.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 || *result[0].(*interface{}) != "alice" {
+		t.Errorf("expect [alice], got %v", result)
+	}
+
+	j, _ = New("error", `$[?(upper(@.x) == "2")]`)
+	j.RegisterFunc("upper", upper)
+	j.InitData(data)
+	if _, err := j.Get(); err == nil || !strings.Contains(err.Error(), "function upper: expect a string, got 2") {
+		t.Errorf("expect the error of the function, got %v", err)
+	}
+
+	j, _ = New("unknown", `$[?(upper(@.name) == "BOB")]`)
+	c := j.Clone()
+	c.RegisterFunc("upper", upper)
+	j.InitData(data)
+	if _, err := j.Get(); err == nil || !strings.Contains(err.Error(), "unknown function upper") {
+		t.Errorf("expect the function to be registered on the clone only, got %v", err)
+	}
+}
+
+func TestNonRefFootprintLeaveItAsItIs(t *testing.T) {
+	var v interface{} = 42.0
+	fp := NewFootprint(&v, nil)
+	if _, err := fp.Expand(); err == nil {
+		t.Errorf("expect a scalar not to expand")
+	}
+	expanded, err := fp.LeaveItAsItIs().Expand()
+	if err != nil || len(expanded) != 1 || *expanded[0].HolderPtr() != 42.0 {
+		t.Fatalf("expect a scalar left as it is to expand to itself, got %v, %v", expanded, err)
+	}
+	if _, err := expanded[0].Expand(); err == nil {
+		t.Errorf("expect a scalar to be left as it is only once")
+	}
+}
+
+func TestGetReport(t *testing.T) {
+	j, err := New("report", `$.store.missing[0]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(ConvertToJsonObj(`{"store": {"book": []}}`))
+	result, err := j.GetReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Values) != 0 {
+		t.Errorf("expect no values, got %v", result.Values)
+	}
+	expectation := []WarningDetail{{Message: "cannot find the field: missing", Segment: ".missing", Index: 1, Char: 7}}
+	if fmt.Sprint(result.Warnings) != fmt.Sprint(expectation) {
+		t.Errorf("expect the warnings %v, got %v", expectation, result.Warnings)
+	}
+
+	j, _ = New("report", `$.store.book`)
+	j.InitData(ConvertToJsonObj(`{"store": {"book": [1]}}`))
+	result, err = j.GetReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(result.Values) != "[[1]]" || len(result.Warnings) != 0 {
+		t.Errorf("expect the values [[1]] without warnings, got %v", result)
+	}
+}
+
+func TestSetWarningHandler(t *testing.T) {
+	j, err := New("handler", `$.store.missing[0]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var handled []WarningDetail
+	j.SetWarningHandler(func(w WarningDetail) {
+		handled = append(handled, w)
+	})
+	j.InitData(ConvertToJsonObj(`{"store": {"book": []}}`))
+	if _, err := j.Get(); err != nil {
+		t.Fatal(err)
+	}
+	expectation := []WarningDetail{{Message: "cannot find the field: missing", Segment: ".missing", Index: 1, Char: 7}}
+	if fmt.Sprint(handled) != fmt.Sprint(expectation) {
+		t.Errorf("expect the handled warnings %v, got %v", expectation, handled)
+	}
+	if len(j.warnings) != 1 {
+		t.Errorf("expect the warning to be kept, got %v", j.warnings)
+	}
+
+	handled = nil
+	c := j.Clone()
+	c.InitData(ConvertToJsonObj(`{}`))
+	if _, err := c.Get(); err != nil {
+		t.Fatal(err)
+	}
+	if len(handled) != 1 || handled[0].Message != "cannot find the field: store" {
+		t.Errorf("expect the clone to call the handler, got %v", handled)
+	}
+}
+
+func TestCloneSecondaryRoot(t *testing.T) {
+	j, err := New("clone", `$[?(@.id == $$.t)].name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitSecondaryData(ConvertToJsonObj(`{"t":2}`))
+	c := j.Clone()
+	c.InitData(ConvertToJsonObj(`[{"id":1,"name":"a"},{"id":2,"name":"b"}]`))
+	result, err := c.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values := resultValues(result); len(values) != 1 || values[0] != "b" {
+		t.Errorf("unexpected result %v", values)
+	}
+
+	j, err = New("then", `$.items`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(ConvertToJsonObj(`{"items":[{"id":1},{"id":2}]}`))
+	j.InitSecondaryData(ConvertToJsonObj(`{"t":2}`))
+	chained, err := j.GetThen(`$[?(@.id == $$.t)].id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values := resultValues(chained); len(values) != 1 || values[0] != 2.0 {
+		t.Errorf("unexpected result %v", values)
+	}
+}
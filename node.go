@@ -17,7 +17,9 @@ limitations under the License.
 package jsonpath
 
 import (
+	"encoding/json"
 	"fmt"
+	"regexp"
 	"strings"
 )
 
@@ -47,31 +49,67 @@ const (
 	NodeRecursive
 	NodeUnion
 	NodeBool
+	NodeFunction
+	NodePseudoField
+	NodeArithmetic
+	NodeKeyRegex
+	NodeRegex
+	NodeLiteral
+	NodeIndexList
+	NodeSecondaryRoot
+	NodeGlobField
+	NodeParent
 )
 
 var NodeTypeName = map[NodeType]string{
-	NodeText:       "NodeText",
-	NodeArray:      "NodeArray",
-	NodeList:       "NodeList",
-	NodeField:      "NodeField",
-	NodeIdentifier: "NodeIdentifier",
-	NodeFilter:     "NodeFilter",
-	NodeInt:        "NodeInt",
-	NodeFloat:      "NodeFloat",
-	NodeWildcard:   "NodeWildcard",
-	NodeRecursive:  "NodeRecursive",
-	NodeUnion:      "NodeUnion",
-	NodeBool:       "NodeBool",
+	NodeText:          "NodeText",
+	NodeArray:         "NodeArray",
+	NodeArrayElement:  "NodeArrayElement",
+	NodeList:          "NodeList",
+	NodeField:         "NodeField",
+	NodeIdentifier:    "NodeIdentifier",
+	NodeFilter:        "NodeFilter",
+	NodeInt:           "NodeInt",
+	NodeFloat:         "NodeFloat",
+	NodeWildcard:      "NodeWildcard",
+	NodeRecursive:     "NodeRecursive",
+	NodeUnion:         "NodeUnion",
+	NodeBool:          "NodeBool",
+	NodeFunction:      "NodeFunction",
+	NodePseudoField:   "NodePseudoField",
+	NodeArithmetic:    "NodeArithmetic",
+	NodeKeyRegex:      "NodeKeyRegex",
+	NodeRegex:         "NodeRegex",
+	NodeLiteral:       "NodeLiteral",
+	NodeIndexList:     "NodeIndexList",
+	NodeSecondaryRoot: "NodeSecondaryRoot",
+	NodeGlobField:     "NodeGlobField",
+	NodeParent:        "NodeParent",
 }
 
 type Node interface {
 	Type() NodeType
 	String() string
+	Position() Pos
+}
+
+// Pos is the byte offset of a node in the expression it is parsed from.
+// The nodes of a filter operand or a union member are parsed from the text of
+// the operand or the member.
+type Pos int
+
+func (p Pos) Position() Pos {
+	return p
+}
+
+func (p *Pos) setPosition(pos Pos) {
+	*p = pos
 }
 
 // ListNode holds a sequence of nodes.
 type ListNode struct {
 	NodeType
+	Pos
 	Nodes []Node // The element nodes in lexical order.
 }
 
@@ -90,6 +128,7 @@ func (l *ListNode) String() string {
 // TextNode holds plain text.
 type TextNode struct {
 	NodeType
+	Pos
 	Text string // The text; may span newlines.
 }
 
@@ -104,6 +143,7 @@ func (t *TextNode) String() string {
 // FieldNode holds field of struct
 type FieldNode struct {
 	NodeType
+	Pos
 	Value string
 }
 
@@ -128,6 +168,7 @@ func (f *FieldNode) String() string {
 // IdentifierNode holds an identifier
 type IdentifierNode struct {
 	NodeType
+	Pos
 	Name string
 }
 
@@ -152,6 +193,7 @@ type ParamsEntry struct {
 // ArrayNode holds start, end, step information for array index selection
 type ArrayNode struct {
 	NodeType
+	Pos
 	Params []ParamsEntry // start, end, step
 }
 
@@ -164,6 +206,7 @@ func newArray(params []ParamsEntry) *ArrayNode {
 
 type ArrayElementNode struct {
 	NodeType
+	Pos
 	ParamsEntry
 }
 
@@ -178,9 +221,14 @@ func (a *ArrayNode) String() string {
 	return fmt.Sprintf("%s: %v", a.Type(), a.Params)
 }
 
+func (a *ArrayElementNode) String() string {
+	return fmt.Sprintf("%s: %v", a.Type(), a.ParamsEntry)
+}
+
 // FilterNode holds operand and operator information for filter
 type FilterNode struct {
 	NodeType
+	Pos
 	Left     *ListNode
 	Right    *ListNode
 	Operator string
@@ -202,6 +250,7 @@ func (f *FilterNode) String() string {
 // IntNode holds integer value
 type IntNode struct {
 	NodeType
+	Pos
 	Value int
 }
 
@@ -216,6 +265,7 @@ func (i *IntNode) String() string {
 // FloatNode holds float value
 type FloatNode struct {
 	NodeType
+	Pos
 	Value float64
 }
 
@@ -230,6 +280,7 @@ func (i *FloatNode) String() string {
 // WildcardNode means a wildcard
 type WildcardNode struct {
 	NodeType
+	Pos
 }
 
 func newWildcard() *WildcardNode {
@@ -243,6 +294,7 @@ func (i *WildcardNode) String() string {
 // RecursiveNode means a recursive descent operator
 type RecursiveNode struct {
 	NodeType
+	Pos
 }
 
 func newRecursive() *RecursiveNode {
@@ -256,6 +308,7 @@ func (r *RecursiveNode) String() string {
 // UnionNode is union of ListNode
 type UnionNode struct {
 	NodeType
+	Pos
 	Nodes []*ListNode
 }
 
@@ -270,6 +323,7 @@ func (u *UnionNode) String() string {
 // BoolNode holds bool value
 type BoolNode struct {
 	NodeType
+	Pos
 	Value bool
 }
 
@@ -280,3 +334,158 @@ func newBool(value bool) *BoolNode {
 func (b *BoolNode) String() string {
 	return fmt.Sprintf("%s: %t", b.Type(), b.Value)
 }
+
+// FunctionNode holds a function call like length(@.items)
+type FunctionNode struct {
+	NodeType
+	Pos
+	Name string
+	Args []*ListNode
+}
+
+func newFunction(name string, args []*ListNode) *FunctionNode {
+	return &FunctionNode{NodeType: NodeFunction, Name: name, Args: args}
+}
+
+func (f *FunctionNode) String() string {
+	return fmt.Sprintf("%s: %s", f.Type(), f.Name)
+}
+
+// PseudoFieldNode holds a pseudo field of the current element like @index
+type PseudoFieldNode struct {
+	NodeType
+	Pos
+	Name string
+}
+
+func newPseudoField(name string) *PseudoFieldNode {
+	return &PseudoFieldNode{NodeType: NodePseudoField, Name: name}
+}
+
+func (p *PseudoFieldNode) String() string {
+	return fmt.Sprintf("%s: @%s", p.Type(), p.Name)
+}
+
+// ArithmeticNode holds the operands and the operator of an arithmetic operation
+type ArithmeticNode struct {
+	NodeType
+	Pos
+	Left     *ListNode
+	Right    *ListNode
+	Operator string
+}
+
+func newArithmetic(left, right *ListNode, operator string) *ArithmeticNode {
+	return &ArithmeticNode{NodeType: NodeArithmetic, Left: left, Right: right, Operator: operator}
+}
+
+func (a *ArithmeticNode) String() string {
+	return fmt.Sprintf("%s: %s %s %s", a.Type(), a.Left, a.Operator, a.Right)
+}
+
+// KeyRegexNode selects the keys of an object matching a regular expression
+type KeyRegexNode struct {
+	NodeType
+	Pos
+	Regexp *regexp.Regexp
+}
+
+func newKeyRegex(re *regexp.Regexp) *KeyRegexNode {
+	return &KeyRegexNode{NodeType: NodeKeyRegex, Regexp: re}
+}
+
+func (k *KeyRegexNode) String() string {
+	return fmt.Sprintf("%s: ~/%s/", k.Type(), k.Regexp)
+}
+
+// GlobFieldNode selects the keys of an object matching a glob pattern like
+// user_*, with the syntax of path.Match
+type GlobFieldNode struct {
+	NodeType
+	Pos
+	Pattern string
+}
+
+func newGlobField(pattern string) *GlobFieldNode {
+	return &GlobFieldNode{NodeType: NodeGlobField, Pattern: pattern}
+}
+
+func (g *GlobFieldNode) String() string {
+	return fmt.Sprintf("%s: %s", g.Type(), g.Pattern)
+}
+
+// ParentNode means ^, which selects the object or the array holding the value
+type ParentNode struct {
+	NodeType
+	Pos
+}
+
+func newParent() *ParentNode {
+	return &ParentNode{NodeType: NodeParent}
+}
+
+func (p *ParentNode) String() string {
+	return p.Type().String()
+}
+
+// RegexNode holds a regex literal, the right operand of =~
+type RegexNode struct {
+	NodeType
+	Pos
+	Regexp *regexp.Regexp
+}
+
+func newRegex(re *regexp.Regexp) *RegexNode {
+	return &RegexNode{NodeType: NodeRegex, Regexp: re}
+}
+
+func (r *RegexNode) String() string {
+	return fmt.Sprintf("%s: /%s/", r.Type(), r.Regexp)
+}
+
+// LiteralNode holds an array or an object literal, an operand of a filter
+// like [0, 0]
+type LiteralNode struct {
+	NodeType
+	Pos
+	Value interface{}
+}
+
+func newLiteral(value interface{}) *LiteralNode {
+	return &LiteralNode{NodeType: NodeLiteral, Value: value}
+}
+
+func (l *LiteralNode) String() string {
+	b, _ := json.Marshal(l.Value)
+	return fmt.Sprintf("%s: %s", l.Type(), b)
+}
+
+// IndexListNode holds the indexes of an index list like [[2,0]], which selects
+// the elements in the order of the indexes
+type IndexListNode struct {
+	NodeType
+	Pos
+	Indexes []int
+}
+
+func newIndexList(indexes []int) *IndexListNode {
+	return &IndexListNode{NodeType: NodeIndexList, Indexes: indexes}
+}
+
+func (l *IndexListNode) String() string {
+	return fmt.Sprintf("%s: %v", l.Type(), l.Indexes)
+}
+
+// SecondaryRootNode means $$, the root of the secondary document
+type SecondaryRootNode struct {
+	NodeType
+	Pos
+}
+
+func newSecondaryRoot() *SecondaryRootNode {
+	return &SecondaryRootNode{NodeType: NodeSecondaryRoot}
+}
+
+func (s *SecondaryRootNode) String() string {
+	return s.Type().String()
+}
@@ -17,8 +17,10 @@ limitations under the License.
 package jsonpath
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"path"
 	"regexp"
 	"strconv"
 	"strings"
@@ -34,12 +36,13 @@ const (
 )
 
 type Parser struct {
-	Name  string
-	Root  *ListNode
-	input string
-	pos   int
-	start int
-	width int
+	Name    string
+	Root    *ListNode
+	input   string
+	pos     int
+	start   int
+	width   int
+	segment int // the start of the segment being parsed
 }
 
 var (
@@ -47,6 +50,29 @@ var (
 	dictKeyRex = regexp.MustCompile(`^['"](.*)['"]$`)
 	//dictKeyRex       = regexp.MustCompile(`^['"]([^']*)['"]$`)
 	sliceOperatorRex = regexp.MustCompile(`^(-?[\d]*)(:-?[\d]*)?(:-?[\d]*)?$`)
+	filterRex        = regexp.MustCompile(`^([^!<>=]+)([!<>=~]+)(.*)$`)
+	firstLastRex     = regexp.MustCompile(`^(first|last)\((\d+)\)$`)
+	nthRex           = regexp.MustCompile(`^nth\( *(\d+) *(?:, *(\d+) *)?\)$`)
+	// filterOperators holds the comparison operators supported by filters
+	filterOperators = map[string]bool{
+		"<":  true,
+		">":  true,
+		"==": true,
+		"!=": true,
+		// === and !== are aliases of == and != for users used to JavaScript,
+		// the comparison is not stricter since the operands are generic json
+		// values, whose types are compared by == anyway
+		"===": true,
+		"!==": true,
+		"=~":  true, // matches a string with a regex like /pattern/flags
+		// like the attribute selectors of CSS, a string starts with, ends
+		// with or contains another
+		"^=": true,
+		"$=": true,
+		"*=": true,
+		"<=": true,
+		">=": true,
+	}
 )
 
 // Parse parsed the given text and return a node Parser.
@@ -146,10 +172,12 @@ func (p *Parser) parseLeftDelim(cur *ListNode) error {
 }
 
 func (p *Parser) parseInsideAction(cur *ListNode) error {
+	p.segment = p.start
 	prefixMap := map[string]func(*ListNode) error{ // 大括号里面可能会有这三种特殊情况, 这些要另开个新的处理流程
 		rightDelim: p.parseRightDelim,
 		"[?(":      p.parseFilter,
 		"..":       p.parseRecursive,
+		".~/":      p.parseKeyRegex,
 	}
 	for prefix, parseFunc := range prefixMap { // 看一看到底是哪一种特殊情况, 用对应的解析方法来处理
 		if strings.HasPrefix(p.input[p.pos:], prefix) {
@@ -162,8 +190,20 @@ func (p *Parser) parseInsideAction(cur *ListNode) error {
 		return fmt.Errorf("unclosed action")
 	case r == ' ': // 遇到空格直接消耗掉
 		p.consumeText()
+	case r == '$' && p.peek() == '$': // $$ is the root of the secondary document
+		p.next()
+		p.consumeText()
+		p.appendNode(cur, newSecondaryRoot())
+	case r == '^': // the parent of the value, e.g. $..price^ selects the objects having a price
+		p.consumeText()
+		p.appendNode(cur, newParent())
 	case r == '@' || r == '$': // 这种字符代表当前的对象, 直接消耗掉, 然后递归后续表达式处理流程
 		p.consumeText()
+		if r == '@' {
+			if name, ok := p.scanPseudoField(); ok {
+				p.appendNode(cur, newPseudoField(name))
+			}
+		}
 	case r == '[':
 		return p.parseArray(cur)
 	case r == '"' || r == '\'':
@@ -182,6 +222,33 @@ func (p *Parser) parseInsideAction(cur *ListNode) error {
 	return p.parseInsideAction(cur) // 递归处理后续字符串
 }
 
+// scanPseudoField consumes the name of a pseudo field following @, which is
+// either index or key
+func (p *Parser) scanPseudoField() (string, bool) {
+	for _, name := range []string{"index", "key"} {
+		rest := p.input[p.pos:]
+		if !strings.HasPrefix(rest, name) {
+			continue
+		}
+		if r, _ := utf8.DecodeRuneInString(rest[len(name):]); isAlphaNumeric(r) {
+			continue
+		}
+		p.pos += len(name)
+		p.consumeText()
+		return name, true
+	}
+	return "", false
+}
+
+// appendNode appends the node to cur, positioned at the start of the segment
+// being parsed
+func (p *Parser) appendNode(cur *ListNode, n Node) {
+	if positioned, ok := n.(interface{ setPosition(Pos) }); ok {
+		positioned.setPosition(Pos(p.segment - len(leftDelim)))
+	}
+	cur.append(n)
+}
+
 // parseRightDelim scans the right delimiter, which is known to be present.
 func (p *Parser) parseRightDelim(cur *ListNode) error { // 遇到右大括号表示处理的结束
 	p.pos += len(rightDelim)
@@ -194,24 +261,114 @@ func (p *Parser) parseIdentifier(cur *ListNode) error {
 	var r rune
 	for {
 		r = p.next()
-		if isTerminator(r) {
+		if isTerminator(r) || r == '(' {
 			p.backup()
 			break
 		}
 	}
 	value := p.consumeText()
 
-	if isBool(value) {
+	if p.peek() == '(' {
+		return p.parseFunction(cur, value)
+	} else if isBool(value) {
 		v, err := strconv.ParseBool(value)
 		if err != nil {
 			return fmt.Errorf("can not parse bool '%s': %s", value, err.Error())
 		}
 
-		cur.append(newBool(v))
+		p.appendNode(cur, newBool(v))
 	} else {
-		cur.append(newIdentifier(value))
+		p.appendNode(cur, newIdentifier(value))
+	}
+
+	return p.parseInsideAction(cur)
+}
+
+// parseFunction scans the arguments of a function call, each argument is an
+// expression parsed on its own
+func (p *Parser) parseFunction(cur *ListNode, name string) error {
+	p.next()
+	p.consumeText() // 消耗掉左小括号
+	var quote rune
+	depth := 0
+	escapeMode := false
+	start := p.pos
+	argStrs := make([]string, 0)
+Loop:
+	for {
+		r := p.next()
+		switch {
+		case r == eof:
+			return fmt.Errorf("unclosed function call %s", name)
+		case escapeMode:
+			escapeMode = false
+		case r == '\\':
+			escapeMode = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(' || r == '[':
+			depth++
+		case (r == ')' || r == ']') && depth > 0:
+			depth--
+		case r == ',' && depth == 0:
+			argStrs = append(argStrs, p.input[start:p.pos-1])
+			start = p.pos
+		case r == ')':
+			argStrs = append(argStrs, p.input[start:p.pos-1])
+			break Loop
+		}
 	}
+	p.consumeText()
 
+	if len(argStrs) == 1 && strings.TrimSpace(argStrs[0]) == "" {
+		argStrs = nil
+	}
+	args := make([]*ListNode, 0, len(argStrs))
+	for _, argStr := range argStrs {
+		if strings.TrimSpace(argStr) == "" {
+			return fmt.Errorf("empty argument of function call %s", name)
+		}
+		parser, err := parseAction("argument", argStr)
+		if err != nil {
+			return err
+		}
+		args = append(args, parser.Root)
+	}
+	p.appendNode(cur, newFunction(name, args))
+	return p.parseInsideAction(cur)
+}
+
+// parseKeyRegex scans the regular expression of a key selector like
+// .~/^config_/ or ..~/^config_/, a slash inside the expression is escaped by a backslash
+func (p *Parser) parseKeyRegex(cur *ListNode) error {
+	p.pos += strings.Index(p.input[p.pos:], "~/") + len("~/")
+	p.consumeText()
+	escapeMode := false
+Loop:
+	for {
+		r := p.next()
+		switch {
+		case r == eof:
+			return fmt.Errorf("unterminated key regex")
+		case escapeMode:
+			escapeMode = false
+		case r == '\\':
+			escapeMode = true
+		case r == '/':
+			break Loop
+		}
+	}
+	text := p.consumeText()
+	pattern := strings.Replace(text[:len(text)-1], `\/`, "/", -1)
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid key regex %s: %v", pattern, err)
+	}
+	p.appendNode(cur, newKeyRegex(re))
 	return p.parseInsideAction(cur)
 }
 
@@ -222,9 +379,12 @@ func (p *Parser) parseRecursive(cur *ListNode) error {
 	}
 	p.pos += len("..")
 	p.consumeText()
-	cur.append(newRecursive())
+	p.appendNode(cur, newRecursive())
+	p.segment = p.start
 	if r := p.peek(); isAlphaNumeric(r) || r == '"' || r == '\'' || r == '*' {
 		return p.parseField(cur)
+	} else if strings.HasPrefix(p.input[p.pos:], "~/") {
+		return p.parseKeyRegex(cur)
 	}
 	return p.parseInsideAction(cur)
 }
@@ -245,12 +405,12 @@ func (p *Parser) parseNumber(cur *ListNode) error {
 	value := p.consumeText()
 	i, err := strconv.Atoi(value)
 	if err == nil {
-		cur.append(newInt(i))
+		p.appendNode(cur, newInt(i))
 		return p.parseInsideAction(cur)
 	}
 	d, err := strconv.ParseFloat(value, 64)
 	if err == nil {
-		cur.append(newFloat(d))
+		p.appendNode(cur, newFloat(d))
 		return p.parseInsideAction(cur)
 	}
 	return fmt.Errorf("cannot parse number %s", value)
@@ -313,6 +473,9 @@ func findRune(rs []rune, target rune) int {
 
 // parseArray scans array index selection
 func (p *Parser) parseArray(cur *ListNode) error {
+	if strings.HasPrefix(strings.TrimLeft(p.input[p.pos:], " "), "[") {
+		return p.parseIndexList(cur)
+	}
 Loop:
 	for {
 		r := p.next()
@@ -331,10 +494,12 @@ Loop:
 		}
 	}
 	text := p.consumeText()
-	text = text[1 : len(text)-1]
+	// the whitespace around the selector and around each union member is
+	// insignificant, the whitespace inside quotes is kept
+	text = strings.TrimSpace(text[1 : len(text)-1])
 	if text == "*" {
 		//text = ":"
-		cur.append(newWildcard())
+		p.appendNode(cur, newWildcard())
 		return p.parseInsideAction(cur)
 	}
 
@@ -343,19 +508,21 @@ Loop:
 	strs := splitByComma(text)
 	if len(strs) > 1 {
 		union := []*ListNode{}
-		for _, str := range strs {
-			parser, err := parseAction("union", fmt.Sprintf("[%s]", strings.Trim(str, " ")))
+		for i, str := range strs {
+			if strings.TrimSpace(str) == "" {
+				return fmt.Errorf("empty union member %d in [%s]", i, text)
+			}
+			parser, err := parseAction("union", fmt.Sprintf("[%s]", strings.TrimSpace(str)))
 			if err != nil {
 				return err
 			}
 			union = append(union, parser.Root)
 		}
-		cur.append(newUnion(union))
+		p.appendNode(cur, newUnion(union))
 		return p.parseInsideAction(cur)
 	}
 
 	// dict key
-	text = strings.TrimSpace(text)
 	value := dictKeyRex.FindStringSubmatch(text)
 	if value != nil {
 		//parser, err := parseAction("arraydict", fmt.Sprintf(".%s", value[1]))
@@ -365,7 +532,13 @@ Loop:
 		//for _, node := range parser.Root.Nodes {
 		//	cur.append(node)
 		//}
-		cur.append(newField(value[1]))
+		// escape sequences like \n and \u00fc are interpreted as in string
+		// literals, other escaped characters are taken literally
+		if key, err := UnquoteExtend(text); err == nil {
+			p.appendNode(cur, &FieldNode{NodeType: NodeField, Value: key})
+		} else {
+			p.appendNode(cur, newField(value[1]))
+		}
 		return p.parseInsideAction(cur)
 	}
 
@@ -394,7 +567,7 @@ Loop:
 				Derived: false,
 			})
 		}
-		cur.append(arrayElement)
+		p.appendNode(cur, arrayElement)
 		return p.parseInsideAction(cur)
 	}
 	params := make([]ParamsEntry, 3)
@@ -418,7 +591,33 @@ Loop:
 			params[i].Value = 0
 		}
 	}
-	cur.append(newArray(params))
+	p.appendNode(cur, newArray(params))
+	return p.parseInsideAction(cur)
+}
+
+// parseIndexList scans an index list like [[2,0]], the outer [ is scanned
+func (p *Parser) parseIndexList(cur *ListNode) error {
+	rest := p.input[p.pos:]
+	start := strings.Index(rest, "[")
+	end := strings.Index(rest, "]")
+	if end < 0 {
+		return fmt.Errorf("unterminated index list")
+	}
+	closing := strings.Index(rest[end+1:], "]")
+	if closing < 0 || strings.TrimSpace(rest[end+1:end+1+closing]) != "" {
+		return fmt.Errorf("unterminated index list %s", rest[:end+1])
+	}
+	indexes := make([]int, 0)
+	for i, str := range strings.Split(rest[start+1:end], ",") {
+		index, err := strconv.Atoi(strings.TrimSpace(str))
+		if err != nil {
+			return fmt.Errorf("index list member %d of %s is not a number", i, rest[start:end+1])
+		}
+		indexes = append(indexes, index)
+	}
+	p.pos += end + 1 + closing + 1
+	p.consumeText()
+	p.appendNode(cur, newIndexList(indexes))
 	return p.parseInsideAction(cur)
 }
 
@@ -426,60 +625,381 @@ Loop:
 func (p *Parser) parseFilter(cur *ListNode) error {
 	p.pos += len("[?(")
 	p.consumeText() // 消耗掉这个[?(
-	begin := false
-	end := false
-	var pair rune
+	var quote rune  // the quote of the string or the slash of the regex literal being scanned
+	depth := 0      // the depth of the parentheses inside the filter, e.g. of a function call
+	escapeMode := false
 
 Loop:
 	for {
 		r := p.next()
-		switch r {
-		case eof, '\n': // filter里面不能有这种东西, 否则乱套了, 报错返回
+		switch {
+		case r == eof || r == '\n': // filter里面不能有这种东西, 否则乱套了, 报错返回
 			return fmt.Errorf("unterminated filter")
-		case '"', '\'': // 双引号和单引号都是是要成对出现的
-			if begin == false {
-				//save the paired rune
-				begin = true
-				pair = r
-				continue
-			}
-			//only add when met paired rune
-			if p.input[p.pos-2] != '\\' && r == pair {
-				end = true
-			}
-		case ')': // 代表filter结束了, 这个右小括号只能出现一次
-			//in rightParser below quotes only appear zero or once
-			//and must be paired at the beginning and end
-			if begin == end {
-				break Loop
+		case escapeMode:
+			escapeMode = false
+		case r == '\\':
+			escapeMode = true
+		case quote != 0: // 引号里面的东西都是字面值
+			if r == quote {
+				quote = 0
 			}
+		case r == '"' || r == '\'' || r == '/': // 双引号和单引号都是是要成对出现的, so are the slashes of a regex literal
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case r == ')': // 代表filter结束了
+			break Loop
 		}
 	}
 	if p.next() != ']' {
 		return fmt.Errorf("unclosed array expect ]")
 	}
-	reg := regexp.MustCompile(`^([^!<>=]+)([!<>=]+)(.+?)$`)
 	text := p.consumeText()
-	text = text[:len(text)-2]             // 提取出整个filter字符串
-	value := reg.FindStringSubmatch(text) // 把filter字符串按照正则表达式里的小括号切分成三个部分: "引用(左表达式)", "符号", "字面值(右表达式)"
+	text = text[:len(text)-2] // 提取出整个filter字符串
+	filter, err := parseComparison(text)
+	if err != nil {
+		return err
+	}
+	p.appendNode(cur, filter)
+	return p.parseInsideAction(cur)
+}
+
+// parseComparison builds the filter node of an existence check or a comparison,
+// or of conditions combined by && and ||. && binds tighter than ||, and
+// parentheses group conditions.
+func parseComparison(text string) (*FilterNode, error) {
+	for _, operator := range []string{"||", "&&"} {
+		if index := indexOperator(text, operator); index >= 0 {
+			return newLogical(text[:index], operator, text[index+len(operator):])
+		}
+	}
+	if inner := strings.TrimSpace(text); isParenthesized(inner) {
+		return parseComparison(inner[1 : len(inner)-1])
+	}
+	if inner := strings.TrimSpace(text); strings.HasPrefix(inner, "!") && !strings.HasPrefix(inner, "!=") {
+		return newNot(inner[1:])
+	}
+	for _, quantifier := range []string{"all", "any"} {
+		if rest, ok := quantified(text, quantifier); ok {
+			return newQuantifier(quantifier, rest)
+		}
+	}
+	if index := indexKeyword(text, "between"); index >= 0 {
+		return newBetween(text[:index], text[index+len("between"):])
+	}
+	if index := indexKeyword(text, "in"); index >= 0 {
+		return newComparison(text[:index], "in", text[index+len("in"):])
+	}
+	value := filterRex.FindStringSubmatch(text) // 把filter字符串按照正则表达式里的小括号切分成三个部分: "引用(左表达式)", "符号", "字面值(右表达式)"
 	if value == nil {
 		parser, err := parseAction("text", text)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		cur.append(newFilter(parser.Root, newList(), "exists"))
-	} else {
-		leftParser, err := parseAction("left", value[1]) // 子parser, 包含了左表达式里的Nodes
+		return newFilter(parser.Root, newList(), "exists"), nil
+	}
+	if left := value[1]; value[2] == "=" && strings.ContainsAny(left[len(left)-1:], "^$*") {
+		// the regex leaves the first character of ^=, $= and *= to the left
+		// operand, as it may also be a part of a path like @.*
+		value[1], value[2] = left[:len(left)-1], left[len(left)-1:]+"="
+	}
+	if !filterOperators[value[2]] {
+		return nil, fmt.Errorf("unrecognized filter operator %s", value[2])
+	}
+	if strings.TrimSpace(value[3]) == "" {
+		return nil, fmt.Errorf("missing the right operand of %s", value[2])
+	}
+	if value[2] == "=~" {
+		return newRegexMatch(value[1], value[3])
+	}
+	return newComparison(value[1], value[2], value[3])
+}
+
+// newLogical parses both conditions combined by a logical operator, each
+// operand of the filter node holds the filter node of a condition
+func newLogical(left, operator, right string) (*FilterNode, error) {
+	if strings.TrimSpace(left) == "" || strings.TrimSpace(right) == "" {
+		return nil, fmt.Errorf("missing a condition of %s", operator)
+	}
+	leftFilter, err := parseComparison(left)
+	if err != nil {
+		return nil, err
+	}
+	rightFilter, err := parseComparison(right)
+	if err != nil {
+		return nil, err
+	}
+	leftNode, rightNode := newList(), newList()
+	leftNode.append(leftFilter)
+	rightNode.append(rightFilter)
+	return newFilter(leftNode, rightNode, operator), nil
+}
+
+// newNot parses the condition negated by !, which binds tighter than && and ||,
+// so it negates an existence check like !@.a or a group like !(@.a && @.b)
+func newNot(condition string) (*FilterNode, error) {
+	if strings.TrimSpace(condition) == "" {
+		return nil, fmt.Errorf("missing the condition of !")
+	}
+	filter, err := parseComparison(condition)
+	if err != nil {
+		return nil, err
+	}
+	operand := newList()
+	operand.append(filter)
+	return newFilter(operand, newList(), "!"), nil
+}
+
+// quantified returns the condition following a quantifier like all in
+// all @.scores[*] > 50, ok is false unless the text starts with it
+func quantified(text, quantifier string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, quantifier) {
+		return "", false
+	}
+	rest := text[len(quantifier):]
+	if trimmed := strings.TrimSpace(rest); trimmed == rest || !strings.ContainsAny(trimmed[:1], "@$(") {
+		return "", false
+	}
+	return rest, true
+}
+
+// newQuantifier parses the comparison following all or any, which compares
+// every value its left operand selects
+func newQuantifier(quantifier, condition string) (*FilterNode, error) {
+	filter, err := parseComparison(condition)
+	if err != nil {
+		return nil, err
+	}
+	switch filter.Operator {
+	case "exists", "!", "&&", "||", "between", "all", "any":
+		return nil, fmt.Errorf("%s expects a comparison", quantifier)
+	}
+	operand := newList()
+	operand.append(filter)
+	return newFilter(operand, newList(), quantifier), nil
+}
+
+// newBetween parses value between low and high, which is value >= low and
+// value <= high, the bounds are checked before the comparisons
+func newBetween(value, bounds string) (*FilterNode, error) {
+	index := indexKeyword(bounds, "and")
+	if index < 0 {
+		return nil, fmt.Errorf("missing the and of between")
+	}
+	if strings.TrimSpace(bounds[:index]) == "" || strings.TrimSpace(bounds[index+len("and"):]) == "" {
+		return nil, fmt.Errorf("missing a bound of between")
+	}
+	lower, err := newComparison(value, ">=", bounds[:index])
+	if err != nil {
+		return nil, err
+	}
+	upper, err := newComparison(value, "<=", bounds[index+len("and"):])
+	if err != nil {
+		return nil, err
+	}
+	leftNode, rightNode := newList(), newList()
+	leftNode.append(lower)
+	rightNode.append(upper)
+	return newFilter(leftNode, rightNode, "between"), nil
+}
+
+// isParenthesized reports whether the parentheses enclose the whole text
+func isParenthesized(text string) bool {
+	if !strings.HasPrefix(text, "(") || !strings.HasSuffix(text, ")") {
+		return false
+	}
+	end := scanTopLevel(text, func(i int, depth int) bool {
+		return depth == 0 && text[i] == ')'
+	})
+	return end == len(text)-1
+}
+
+// newRegexMatch parses the operands of =~, the right one is a regex literal
+func newRegexMatch(left, right string) (*FilterNode, error) {
+	leftNode, err := parseOperand("left", left)
+	if err != nil {
+		return nil, err
+	}
+	re, err := parseRegex(strings.TrimSpace(right))
+	if err != nil {
+		return nil, err
+	}
+	rightNode := newList()
+	rightNode.append(newRegex(re))
+	return newFilter(leftNode, rightNode, "=~"), nil
+}
+
+// regexFlags maps the flags following a regex literal to the inline flags of
+// the regexp package
+var regexFlags = map[rune]string{
+	'i': "i", // case-insensitive
+	'm': "m", // ^ and $ match the begin and the end of lines
+	's': "s", // . matches \n
+}
+
+// parseRegex compiles a regex literal like /pattern/flags, a slash inside the
+// pattern is escaped by a backslash
+func parseRegex(text string) (*regexp.Regexp, error) {
+	end := strings.LastIndex(text, "/")
+	if !strings.HasPrefix(text, "/") || end == 0 {
+		return nil, fmt.Errorf("the right operand of =~ must be a regex like /pattern/, got %s", text)
+	}
+	pattern := strings.Replace(text[1:end], `\/`, "/", -1)
+	flags := ""
+	for _, r := range text[end+1:] {
+		flag, ok := regexFlags[r]
+		if !ok {
+			return nil, fmt.Errorf("invalid regex flag %c in %s", r, text)
+		}
+		if !strings.Contains(flags, flag) {
+			flags += flag
+		}
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex %s: %v", text, err)
+	}
+	return re, nil
+}
+
+// newComparison parses both operands of a comparison
+func newComparison(left, operator, right string) (*FilterNode, error) {
+	leftNode, err := parseOperand("left", left) // 子parser, 包含了左表达式里的Nodes
+	if err != nil {
+		return nil, err
+	}
+	rightNode, err := parseOperand("right", right)
+	if err != nil {
+		return nil, err
+	}
+	return newFilter(leftNode, rightNode, operator), nil
+}
+
+// parseOperand parses an operand of a comparison, which may be the remainder
+// of two expressions separated by %, like @index % 2 or @index%2
+func parseOperand(name, text string) (*ListNode, error) {
+	if literal, ok := parseLiteral(text); ok {
+		operand := newList()
+		operand.append(newLiteral(literal))
+		return operand, nil
+	}
+	// % is left associative, @.a % 2 % 2 is (@.a % 2) % 2
+	if index := lastIndexOperator(text, "%"); index >= 0 {
+		left, err := parseOperand(name, text[:index])
 		if err != nil {
-			return err
+			return nil, err
 		}
-		rightParser, err := parseAction("right", value[3])
+		right, err := parseOperand(name, text[index+len("%"):])
 		if err != nil {
-			return err
+			return nil, err
 		}
-		cur.append(newFilter(leftParser.Root, rightParser.Root, value[2]))
+		operand := newList()
+		operand.append(newArithmetic(left, right, "%"))
+		return operand, nil
 	}
-	return p.parseInsideAction(cur)
+	// an operand like .5 is a number without its leading zero, a path in a
+	// filter always starts with @ or $ before its first field
+	if trimmed := strings.TrimSpace(text); len(trimmed) > 1 && trimmed[0] == '.' && unicode.IsDigit(rune(trimmed[1])) {
+		text = "0" + trimmed
+	}
+	parser, err := parseAction(name, text)
+	if err != nil {
+		return nil, err
+	}
+	return parser.Root, nil
+}
+
+// parseLiteral decodes an operand which is an array or an object literal in
+// json, like [0, 0] or {"a": 1}. An operand like ['a'] is not valid json, so
+// it is still a path.
+func parseLiteral(text string) (interface{}, bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "[") && !strings.HasPrefix(text, "{") {
+		return nil, false
+	}
+	var literal interface{}
+	if err := json.Unmarshal([]byte(text), &literal); err != nil {
+		return nil, false
+	}
+	return literal, true
+}
+
+// indexKeyword returns the index of the first keyword which is surrounded by
+// spaces and is neither quoted, in a regex literal nor nested in brackets, or
+// -1 if there is none.
+func indexKeyword(text, keyword string) int {
+	return scanTopLevel(text, func(i int, depth int) bool {
+		if depth != 0 || i == 0 || !isSpace(rune(text[i-1])) || !strings.HasPrefix(text[i:], keyword) {
+			return false
+		}
+		end := i + len(keyword)
+		return end < len(text) && isSpace(rune(text[end]))
+	})
+}
+
+// indexOperator returns the index of the first operator which is neither
+// quoted nor nested in brackets, or -1 if there is none.
+func indexOperator(text, operator string) int {
+	return scanTopLevel(text, func(i int, depth int) bool {
+		return depth == 0 && strings.HasPrefix(text[i:], operator)
+	})
+}
+
+// lastIndexOperator is like indexOperator, but it returns the index of the
+// last operator
+func lastIndexOperator(text, operator string) int {
+	last := -1
+	scanTopLevel(text, func(i int, depth int) bool {
+		if depth == 0 && strings.HasPrefix(text[i:], operator) {
+			last = i
+		}
+		return false
+	})
+	return last
+}
+
+// scanTopLevel returns the index of the first rune outside quotes and regex
+// literals which matches, or -1 if there is none. depth is the nesting of the rune in
+// parentheses and brackets, a closing one is at the depth of the opening one.
+func scanTopLevel(text string, match func(i int, depth int) bool) int {
+	var quote rune
+	depth := 0
+	escapeMode := false
+	for i, r := range text {
+		switch {
+		case escapeMode:
+			escapeMode = false
+		case r == '\\':
+			escapeMode = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'' || r == '/':
+			quote = r
+		case r == '(' || r == '[':
+			if match(i, depth) {
+				return i
+			}
+			depth++
+		case r == ')' || r == ']':
+			depth--
+			if match(i, depth) {
+				return i
+			}
+		default:
+			if match(i, depth) {
+				return i
+			}
+		}
+	}
+	return -1
 }
 
 // parseQuote unquotes string inside double or single quote
@@ -489,11 +1009,10 @@ Loop:
 		switch p.next() {
 		case eof, '\n':
 			return fmt.Errorf("unterminated quoted string")
+		case '\\':
+			p.next() // an escaped rune never ends the string
 		case end:
-			//if it's not escape break the Loop
-			if p.input[p.pos-2] != '\\' {
-				break Loop
-			}
+			break Loop
 		}
 	}
 	value := p.consumeText()       // 取出整个引号字符串
@@ -501,24 +1020,105 @@ Loop:
 	if err != nil {
 		return fmt.Errorf("unquote string %s error %v", value, err)
 	}
-	cur.append(newText(s))
+	p.appendNode(cur, newText(s))
 	return p.parseInsideAction(cur)
 }
 
 // parseField scans a field until a terminator
 func (p *Parser) parseField(cur *ListNode) error { // 处理属性成员类型
 	p.consumeText() // 先消耗掉这个'.'
-	for p.advance() {
+	if strings.HasPrefix(p.input[p.pos:], "nth(") {
+		// the arguments of nth are separated by a comma, which ends a name
+		for r := p.next(); r != ')' && r != eof; r = p.next() {
+		}
+	} else {
+		for p.advance() {
+		}
 	}
 	value := p.consumeText() // 把属性成员的名字消耗掉, 把名字进行下面的处理
 	if value == "*" {        // 如果名字是个通配符
-		cur.append(newWildcard())
+		p.appendNode(cur, newWildcard())
+	} else if m := firstLastRex.FindStringSubmatch(value); m != nil {
+		n, err := strconv.Atoi(m[2])
+		if err != nil {
+			return fmt.Errorf("invalid count of %s: %s", m[1], m[2])
+		}
+		p.appendNode(cur, newArray(firstLastParams(m[1], n)))
+	} else if strings.HasPrefix(value, "nth(") {
+		params, err := nthParams(value)
+		if err != nil {
+			return err
+		}
+		p.appendNode(cur, newArray(params))
+	} else if isGlob(value) {
+		if _, err := path.Match(value, ""); err != nil {
+			return fmt.Errorf("invalid glob %s: %v", value, err)
+		}
+		p.appendNode(cur, newGlobField(value))
 	} else { // 普通名字
-		cur.append(newField(strings.Replace(value, "\\", "", -1)))
+		p.appendNode(cur, newField(value)) // newField unescapes the name
 	}
 	return p.parseInsideAction(cur) // 处理后续东西
 }
 
+// isGlob reports whether a field name has an unescaped * or ?, which makes it
+// a glob matching the keys of an object
+func isGlob(value string) bool {
+	escapeMode := false
+	for _, r := range value {
+		switch {
+		case escapeMode:
+			escapeMode = false
+		case r == '\\':
+			escapeMode = true
+		case r == '*' || r == '?':
+			return true
+		}
+	}
+	return false
+}
+
+// nthParams returns the params of the slice nth(step) or nth(step, offset)
+// selects, which is every step-th element from the offset like [offset::step]
+func nthParams(value string) ([]ParamsEntry, error) {
+	m := nthRex.FindStringSubmatch(value)
+	if m == nil {
+		return nil, fmt.Errorf("invalid nth, expect nth(step) or nth(step, offset)")
+	}
+	step, err := strconv.Atoi(m[1])
+	if err != nil || step == 0 {
+		return nil, fmt.Errorf("invalid step of nth: %s", m[1])
+	}
+	offset := 0
+	if m[2] != "" {
+		if offset, err = strconv.Atoi(m[2]); err != nil {
+			return nil, fmt.Errorf("invalid offset of nth: %s", m[2])
+		}
+	}
+	return []ParamsEntry{
+		{Value: offset, Known: true},
+		{},
+		{Value: step, Known: true},
+	}, nil
+}
+
+// firstLastParams returns the params of the slice selecting the first or the
+// last n elements, first(n) is [:n] and last(n) is [-n:]
+func firstLastParams(name string, n int) []ParamsEntry {
+	params := make([]ParamsEntry, 3)
+	switch {
+	case name == "first":
+		params[1] = ParamsEntry{Value: n, Known: true}
+	case n == 0:
+		// [-0:] would be the whole array, [0:0] is none of it
+		params[0] = ParamsEntry{Value: 0, Known: true}
+		params[1] = ParamsEntry{Value: 0, Known: true}
+	default:
+		params[0] = ParamsEntry{Value: -n, Known: true}
+	}
+	return params
+}
+
 // advance scans until next non-escaped terminator
 func (p *Parser) advance() bool { // 前进知道遇到了分隔符
 	r := p.next()
@@ -537,7 +1137,7 @@ func isTerminator(r rune) bool { // 判断是否遇到了分隔符
 		return true
 	}
 	switch r {
-	case eof, '.', ',', '[', ']', '$', '@', '{', '}':
+	case eof, '.', ',', '[', ']', '$', '@', '{', '}', '^':
 		return true
 	}
 	return false
@@ -0,0 +1,195 @@
+package jsonpath
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// formatPath renders the breadcrumbs of a footprint in the canonical bracket
+// notation, e.g. $['store']['book'][0]['price']. The first breadcrumb is the
+// slot of the data holder, so it is rendered as the root $.
+func formatPath(breadcrumbs []interface{}) string {
+	sb := strings.Builder{}
+	sb.WriteString("$")
+	for i, b := range breadcrumbs {
+		if i == 0 {
+			continue
+		}
+		switch b := b.(type) {
+		case int:
+			sb.WriteString("[" + strconv.Itoa(b) + "]")
+		case string:
+			sb.WriteString("['" + escapeKey(b) + "']")
+		}
+	}
+	return sb.String()
+}
+
+// BuildPath renders segments as a path in the canonical bracket notation, a
+// string segment is a key and an int segment is an index, e.g. "a", 0 is
+// $['a'][0]. The keys are escaped, so the path parses back to the segments.
+func BuildPath(segments ...interface{}) string {
+	return formatPath(append([]interface{}{0}, segments...))
+}
+
+// selectedPaths returns the paths of the values selected by a footprint,
+// which are the values UpdateAll overwrites.
+func selectedPaths(footprint Footprint) [][]interface{} {
+	result := make([][]interface{}, 0)
+	switch fp := footprint.(type) {
+	case MapFootprint:
+		for _, sk := range fp.SelectionKeys {
+			result = append(result, appendPath(fp.Path, sk.Key))
+		}
+	case ArrayFootprint:
+		for _, si := range fp.SelectionIndexes {
+			result = append(result, appendPath(fp.Path, si.Index))
+		}
+	case StructFootprint:
+		for _, key := range fp.SelectionKeys {
+			result = append(result, appendPath(fp.Path, key))
+		}
+	}
+	return result
+}
+
+// escapeKey escapes the characters which cannot appear verbatim inside a
+// single quoted key: the backslash, the quote and the control characters,
+// which are written as \n, \t, \r or \uXXXX.
+func escapeKey(key string) string {
+	sb := strings.Builder{}
+	for _, r := range key {
+		switch {
+		case r == '\\' || r == '\'':
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case unicode.IsControl(r):
+			fmt.Fprintf(&sb, `\u%04x`, r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// lessPath orders breadcrumbs segment by segment, comparing indexes
+// numerically and keys lexically.
+func lessPath(x, y []interface{}) bool {
+	for i := 0; i < len(x) && i < len(y); i++ {
+		xi, xIsIndex := x[i].(int)
+		yi, yIsIndex := y[i].(int)
+		switch {
+		case xIsIndex && yIsIndex:
+			if xi != yi {
+				return xi < yi
+			}
+		case xIsIndex != yIsIndex:
+			return xIsIndex
+		default:
+			if xk, yk := x[i].(string), y[i].(string); xk != yk {
+				return xk < yk
+			}
+		}
+	}
+	return len(x) < len(y)
+}
+
+// LeafPaths returns the canonical path of every scalar value in data, it is
+// effectively $..* restricted to the values which are neither objects nor
+// arrays. The paths are sorted so that the result is stable.
+func LeafPaths(data interface{}) []string {
+	var holder interface{} = []interface{}{data}
+	root, _ := NewFootprint(&holder, nil).SelectAll()
+	documents, _ := root.Expand()
+
+	footprints := make([]Footprint, 0)
+	for _, document := range documents {
+		new(Jsonpath).recursivelyCollectFootprint(document, &footprints)
+	}
+
+	leaves := make([][]interface{}, 0)
+	for _, footprint := range footprints {
+		if _, ok := footprint.(NonRefFootprint); ok {
+			leaves = append(leaves, footprint.HolderPath())
+		}
+	}
+	sort.Slice(leaves, func(i, k int) bool {
+		return lessPath(leaves[i], leaves[k])
+	})
+
+	result := make([]string, len(leaves))
+	for i, leaf := range leaves {
+		result[i] = formatPath(leaf)
+	}
+	return result
+}
+
+// Diff returns the canonical paths where b differs from a, each prefixed by
+// the kind of the difference: "+ " for a value only b has, "- " for a value
+// only a has and "~ " for a value which is changed. Objects and arrays are
+// compared member by member, so only the innermost differences are reported.
+// The paths are sorted like LeafPaths.
+func Diff(a, b interface{}) []string {
+	type difference struct {
+		kind string
+		path []interface{}
+	}
+	differences := make([]difference, 0)
+	var diff func(a, b interface{}, path []interface{})
+	diff = func(a, b interface{}, path []interface{}) {
+		switch x := a.(type) {
+		case map[string]interface{}:
+			if y, ok := b.(map[string]interface{}); ok {
+				for k, v := range x {
+					if w, ok := y[k]; ok {
+						diff(v, w, appendPath(path, k))
+					} else {
+						differences = append(differences, difference{"-", appendPath(path, k)})
+					}
+				}
+				for k := range y {
+					if _, ok := x[k]; !ok {
+						differences = append(differences, difference{"+", appendPath(path, k)})
+					}
+				}
+				return
+			}
+		case []interface{}:
+			if y, ok := b.([]interface{}); ok {
+				for i := 0; i < len(x) || i < len(y); i++ {
+					switch {
+					case i >= len(y):
+						differences = append(differences, difference{"-", appendPath(path, i)})
+					case i >= len(x):
+						differences = append(differences, difference{"+", appendPath(path, i)})
+					default:
+						diff(x[i], y[i], appendPath(path, i))
+					}
+				}
+				return
+			}
+		}
+		if !Equal(a, b) {
+			differences = append(differences, difference{"~", path})
+		}
+	}
+	diff(a, b, []interface{}{0})
+
+	sort.Slice(differences, func(i, k int) bool {
+		return lessPath(differences[i].path, differences[k].path)
+	})
+	result := make([]string, len(differences))
+	for i, d := range differences {
+		result[i] = d.kind + " " + formatPath(d.path)
+	}
+	return result
+}
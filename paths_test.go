@@ -0,0 +1,103 @@
+package jsonpath
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLeafPaths(t *testing.T) {
+	expectation := []string{
+		"$['store']['bicycle']['color']",
+		"$['store']['bicycle']['price']",
+	}
+	for i, book := range []string{"0", "1", "2", "3"} {
+		fields := []string{"author", "category", "price", "title"}
+		if i >= 2 {
+			fields = []string{"author", "category", "isbn", "price", "title"}
+		}
+		for _, field := range fields {
+			expectation = append(expectation, "$['store']['book']["+book+"]['"+field+"']")
+		}
+	}
+
+	paths := LeafPaths(ConvertToJsonObj(bookstoreData))
+	if !reflect.DeepEqual(paths, expectation) {
+		t.Errorf("unexpected leaf paths: %v", paths)
+	}
+}
+
+func TestLeafPathsOfNestedArrays(t *testing.T) {
+	data := ConvertToJsonObj(`{"a": [[1, {"b'c": null}], [], {}], "d": 2}`)
+	expectation := []string{
+		"$['a'][0][0]",
+		"$['a'][0][1]['b\\'c']",
+		"$['d']",
+	}
+	paths := LeafPaths(data)
+	if !reflect.DeepEqual(paths, expectation) {
+		t.Errorf("unexpected leaf paths: %v", paths)
+	}
+	if paths := LeafPaths(ConvertToJsonObj(`42`)); !reflect.DeepEqual(paths, []string{"$"}) {
+		t.Errorf("unexpected leaf paths of a scalar document: %v", paths)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := ConvertToJsonObj(`{"name": "app", "spec": {"replicas": 1, "ports": [80, 443]}, "old": true}`)
+	b := ConvertToJsonObj(`{"name": "app", "spec": {"replicas": 3, "ports": [80]}, "labels": {"tier": "web"}}`)
+	expectation := []string{
+		"+ $['labels']",
+		"- $['old']",
+		"- $['spec']['ports'][1]",
+		"~ $['spec']['replicas']",
+	}
+	if diff := Diff(a, b); !reflect.DeepEqual(diff, expectation) {
+		t.Errorf("unexpected diff: %v", diff)
+	}
+	if diff := Diff(a, a); len(diff) != 0 {
+		t.Errorf("expect no diff for the same document, got %v", diff)
+	}
+	if diff := Diff(ConvertToJsonObj(`{"a": [1]}`), ConvertToJsonObj(`{"a": {"0": 1}}`)); !reflect.DeepEqual(diff, []string{"~ $['a']"}) {
+		t.Errorf("expect the changed type to be reported, got %v", diff)
+	}
+	if diff := Diff(ConvertToJsonObj(`1`), ConvertToJsonObj(`2`)); !reflect.DeepEqual(diff, []string{"~ $"}) {
+		t.Errorf("expect the changed root to be reported, got %v", diff)
+	}
+}
+
+func TestBuildPath(t *testing.T) {
+	cases := []struct {
+		segments    []interface{}
+		expectation string
+	}{
+		{[]interface{}{"a", "b", 0, "c.d"}, `$['a']['b'][0]['c.d']`},
+		{[]interface{}{"it's", `a\b`, "[x]"}, `$['it\'s']['a\\b']['[x]']`},
+		{nil, `$`},
+		{[]interface{}{"line\nbreak", "tab\there", "bell\a", "\x00"}, `$['line\nbreak']['tab\there']['bell\u0007']['\u0000']`},
+	}
+	for _, c := range cases {
+		path := BuildPath(c.segments...)
+		if path != c.expectation {
+			t.Errorf("%v: expect %s, got %s", c.segments, c.expectation, path)
+		}
+	}
+
+	data := ConvertToJsonObj(`{"it's": {"a\\b": [{"[x]": 1}]}}`)
+	j, err := New("built", BuildPath("it's", `a\b`, 0, "[x]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(data)
+	if value, err := j.GetSingle(); err != nil || value != 1.0 {
+		t.Errorf("expect the built path to select 1, got %v, %v", value, err)
+	}
+
+	j, err = New("control", BuildPath("line\nbreak", "\r\t\x1b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(map[string]interface{}{"line\nbreak": map[string]interface{}{"\r\t\x1b": 2.0}})
+	if value, err := j.GetSingle(); err != nil || value != 2.0 {
+		t.Errorf("expect the built path with control characters to select 2, got %v, %v", value, err)
+	}
+}
@@ -0,0 +1,114 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// rawNode holds the text of a json value as it is in the document, the
+// members or the elements of an object or an array are split off on demand
+type rawNode struct {
+	raw      json.RawMessage
+	split    bool
+	members  map[string]*rawNode
+	elements []*rawNode
+}
+
+// child returns the node of the member or the element at key, ok is false if
+// the value has none
+func (n *rawNode) child(key interface{}) (*rawNode, bool) {
+	if !n.split {
+		n.split = true
+		var members map[string]json.RawMessage
+		var elements []json.RawMessage
+		if json.Unmarshal(n.raw, &members) == nil {
+			n.members = make(map[string]*rawNode, len(members))
+			for k, raw := range members {
+				n.members[k] = &rawNode{raw: raw}
+			}
+		} else if json.Unmarshal(n.raw, &elements) == nil {
+			n.elements = make([]*rawNode, len(elements))
+			for i, raw := range elements {
+				n.elements[i] = &rawNode{raw: raw}
+			}
+		}
+	}
+	switch key := key.(type) {
+	case string:
+		child, ok := n.members[key]
+		return child, ok
+	case int:
+		if key < 0 || key >= len(n.elements) {
+			return nil, false
+		}
+		return n.elements[key], true
+	}
+	return nil, false
+}
+
+// find returns the node of the value at the breadcrumbs of a footprint, which
+// start with the slot of the holder
+func (n *rawNode) find(path []interface{}) (*rawNode, bool) {
+	if len(path) == 0 {
+		return nil, false
+	}
+	node := n
+	for _, key := range path[1:] {
+		child, ok := node.child(key)
+		if !ok {
+			return nil, false
+		}
+		node = child
+	}
+	return node, true
+}
+
+// InitRawJSON is like InitJSON, but it keeps the text of the json as well,
+// so that GetRaw returns the matched values exactly as they are written,
+// with their spacing, their key order and their number formatting.
+func (j *Jsonpath) InitRawJSON(jsonStr string) error {
+	if err := j.InitJSON(jsonStr); err != nil {
+		return err
+	}
+	j.raw = &rawNode{raw: json.RawMessage(strings.TrimSpace(jsonStr))}
+	return nil
+}
+
+// GetRaw is like Get, but it returns the text of every matched value as it is
+// in the json given to InitRawJSON. A value which is not in the text, like
+// the result of a function, is marshaled instead. The text is dropped once the
+// data is changed by Set, SetIfAbsent or Merge, or replaced by InitData, and a
+// path selecting from the secondary document by $$ is rejected, since only the
+// data has its text.
+func (j *Jsonpath) GetRaw() ([]json.RawMessage, error) {
+	if j.raw == nil {
+		return nil, fmt.Errorf("cannot get the raw json of %s, it is not initialized by InitRawJSON or the data is changed since", j.name)
+	}
+	if j.parser != nil {
+		for _, segment := range j.parser.Root.Nodes[0].(*ListNode).Nodes {
+			if segment.Type() == NodeSecondaryRoot {
+				return nil, fmt.Errorf("cannot get the raw json of %s, $$ has no text", j.name)
+			}
+		}
+	}
+	j.writeMode = false
+	footprints, err := j.FindResult()
+	if err != nil {
+		return nil, err
+	}
+	footprints = expandFootprints(footprints, true)
+	result := make([]json.RawMessage, 0, len(footprints))
+	for _, footprint := range footprints {
+		if node, ok := j.raw.find(footprint.HolderPath()); ok {
+			result = append(result, node.raw)
+			continue
+		}
+		b, err := json.Marshal(*footprint.HolderPtr())
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, b)
+	}
+	return result, nil
+}
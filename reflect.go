@@ -0,0 +1,325 @@
+package jsonpath
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// newChildFootprint creates the footprint of a value found in the data. Go
+// structs are selected through reflection, other Go slices and maps are
+// converted to generic json values. The generic json values never reach the
+// reflection.
+func newChildFootprint(ptr *interface{}, virtualInfo interface{}) Footprint {
+	switch (*ptr).(type) {
+	case nil, string, float64, bool, int, map[string]interface{}, []interface{}:
+		return NewFootprint(ptr, virtualInfo)
+	}
+	v := reflect.ValueOf(*ptr)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return StructFootprint{Ref: ptr}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			break
+		}
+		arr := make([]interface{}, v.Len())
+		for i := range arr {
+			arr[i] = v.Index(i).Interface()
+		}
+		*ptr = arr
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.IsNil() {
+			break
+		}
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = iter.Value().Interface()
+		}
+		*ptr = m
+	}
+	return NewFootprint(ptr, virtualInfo)
+}
+
+// normalizeMaps replaces every map[interface{}]interface{} in value, which
+// some decoders produce, with a map[string]interface{} whose keys are
+// converted by StringKey. The other values are kept, the maps and the slices
+// holding a converted map are changed in place. path is the breadcrumbs of
+// value, it is reused as a stack, and warn is called for every key which
+// cannot be converted or which clashes with another key once converted.
+func normalizeMaps(value interface{}, path []interface{}, warn func(string)) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, v := range value {
+			if converted := normalizeMaps(v, append(path, key), warn); isInterfaceMap(v) {
+				value[key] = converted
+			}
+		}
+	case []interface{}:
+		for i, v := range value {
+			if converted := normalizeMaps(v, append(path, i), warn); isInterfaceMap(v) {
+				value[i] = converted
+			}
+		}
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			key, err := StringKey(k)
+			if err != nil {
+				warn(fmt.Sprintf("%v, the key is dropped at %s", err, formatPath(path)))
+				continue
+			}
+			if _, ok := m[key]; ok {
+				warn(fmt.Sprintf("the key %s is duplicated once converted to a string, one of the values is dropped at %s", key, formatPath(path)))
+			}
+			m[key] = normalizeMaps(v, append(path, key), warn)
+		}
+		return m
+	}
+	return value
+}
+
+func isInterfaceMap(value interface{}) bool {
+	_, ok := value.(map[interface{}]interface{})
+	return ok
+}
+
+// StringKey converts a key of a map[interface{}]interface{} to a string, like
+// 1 to "1" and true to "true", only scalar keys can be converted
+func StringKey(key interface{}) (string, error) {
+	switch key := key.(type) {
+	case string:
+		return key, nil
+	case nil:
+		return "null", nil
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(key), nil
+	case time.Time:
+		return key.Format(time.RFC3339Nano), nil
+	}
+	return "", fmt.Errorf("cannot use a key of %T", key)
+}
+
+// structFields indexes the fields of a struct type by their json names, it is
+// cached per type since building it walks the embedded structs
+var structFields sync.Map // map[reflect.Type]*fieldIndex
+
+// fieldIndex holds the json names of the fields of a struct type and the index
+// sequences reflect.Value.FieldByIndex takes to reach them
+type fieldIndex struct {
+	names   []string
+	indexes map[string][]int
+}
+
+// typeFields returns the index of the fields of a struct type. The fields of
+// embedded structs are promoted like encoding/json does: a shallower field
+// hides a deeper one, and of the fields at the same depth a tagged one wins,
+// otherwise none of them is visible.
+func typeFields(t reflect.Type) *fieldIndex {
+	if fi, ok := structFields.Load(t); ok {
+		return fi.(*fieldIndex)
+	}
+	type candidate struct {
+		index  []int
+		tagged bool
+		count  int
+	}
+	fi := &fieldIndex{indexes: make(map[string][]int)}
+	visited := map[reflect.Type]bool{}
+	current := []struct {
+		t     reflect.Type
+		index []int
+	}{{t, nil}}
+	for len(current) > 0 {
+		next := current[:0:0]
+		level := map[string]*candidate{}
+		order := make([]string, 0)
+		for _, s := range current {
+			if visited[s.t] {
+				continue
+			}
+			visited[s.t] = true
+			for i := 0; i < s.t.NumField(); i++ {
+				field := s.t.Field(i)
+				index := append(append([]int{}, s.index...), i)
+				tag := field.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name := strings.Split(tag, ",")[0]
+				if field.Anonymous && name == "" {
+					ft := field.Type
+					if ft.Kind() == reflect.Ptr {
+						ft = ft.Elem()
+					}
+					if ft.Kind() == reflect.Struct {
+						next = append(next, struct {
+							t     reflect.Type
+							index []int
+						}{ft, index})
+						continue
+					}
+				}
+				if !field.IsExported() {
+					continue
+				}
+				if name == "" {
+					name = field.Name
+				}
+				c, ok := level[name]
+				switch {
+				case !ok:
+					level[name] = &candidate{index: index, tagged: tag != "", count: 1}
+					order = append(order, name)
+				case tag != "" && !c.tagged:
+					*c = candidate{index: index, tagged: true, count: 1}
+				case (tag != "") == c.tagged:
+					c.count++
+				}
+			}
+		}
+		for _, name := range order {
+			if _, ok := fi.indexes[name]; ok {
+				continue
+			}
+			if c := level[name]; c.count == 1 {
+				fi.indexes[name] = c.index
+				fi.names = append(fi.names, name)
+			} else {
+				// the ambiguous name still hides the deeper fields
+				fi.indexes[name] = nil
+			}
+		}
+		current = next
+	}
+	for name, index := range fi.indexes {
+		if index == nil {
+			delete(fi.indexes, name)
+		}
+	}
+	actual, _ := structFields.LoadOrStore(t, fi)
+	return actual.(*fieldIndex)
+}
+
+// structFieldNames returns the json names of the fields of a struct
+func structFieldNames(v reflect.Value) []string {
+	return append([]string{}, typeFields(v.Type()).names...)
+}
+
+// structField returns the field of a struct whose json name is name, ok is
+// false when there is no such field or it is promoted through a nil pointer
+func structField(v reflect.Value, name string) (reflect.Value, bool) {
+	index, ok := typeFields(v.Type()).indexes[name]
+	if !ok {
+		return reflect.Value{}, false
+	}
+	field, err := v.FieldByIndexErr(index)
+	if err != nil {
+		return reflect.Value{}, false
+	}
+	return field, true
+}
+
+// structValue returns the struct held by ptr, dereferencing a pointer
+func structValue(ptr *interface{}) reflect.Value {
+	v := reflect.ValueOf(*ptr)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	return v
+}
+
+// StructFootprint selects the fields of a Go struct by their json names. The
+// fields are read through reflection, so they cannot be set.
+type StructFootprint struct {
+	leaveItAsItIs bool
+	Ref           *interface{}
+	SelectionKeys []string
+	Path          []interface{} // keys and indexes leading from the data holder to Ref
+	parent        Footprint     // the footprint of the value holding Ref, see Parent
+}
+
+func (sfp StructFootprint) LeaveItAsItIs() Footprint {
+	sfp.leaveItAsItIs = true
+	return sfp
+}
+
+func (sfp StructFootprint) Expand() ([]Footprint, error) {
+	if sfp.leaveItAsItIs {
+		sfp.leaveItAsItIs = false
+		return []Footprint{sfp}, nil
+	}
+	if len(sfp.SelectionKeys) == 0 {
+		return nil, nil
+	}
+	result := make([]Footprint, 0)
+	v := structValue(sfp.Ref)
+	holder := sfp
+	holder.SelectionKeys = nil
+	for _, key := range sfp.SelectionKeys {
+		field, ok := structField(v, key)
+		if !ok {
+			continue
+		}
+		value := field.Interface()
+		result = append(result, newChildFootprint(&value, nil).WithHolderPath(appendPath(sfp.Path, key)).WithParent(holder))
+	}
+	return result, nil
+}
+
+func (sfp StructFootprint) HolderPtr() *interface{} {
+	return sfp.Ref
+}
+
+func (sfp StructFootprint) HolderPath() []interface{} {
+	return sfp.Path
+}
+
+func (sfp StructFootprint) WithHolderPath(path []interface{}) Footprint {
+	sfp.Path = path
+	return sfp
+}
+
+func (sfp StructFootprint) Parent() Footprint {
+	return sfp.parent
+}
+
+func (sfp StructFootprint) WithParent(parent Footprint) Footprint {
+	sfp.parent = parent
+	return sfp
+}
+
+func (sfp StructFootprint) UpdateAll(data interface{}) error {
+	return errors.New("cannot set a field of a struct")
+}
+
+func (sfp StructFootprint) UpdateOne(data interface{}, keyOrIndex interface{}) error {
+	return errors.New("cannot set a field of a struct")
+}
+
+func (sfp StructFootprint) SelectAll() (Footprint, error) {
+	names := structFieldNames(structValue(sfp.Ref))
+	sort.Strings(names)
+	sfp.SelectionKeys = names
+	return sfp, nil
+}
+
+func (sfp StructFootprint) IsVirtual() bool {
+	return false
+}
+
+func (sfp StructFootprint) EnforceArraySelection(size int) error {
+	return fmt.Errorf("cannot set a field of the struct at %s", formatPath(sfp.Path))
+}
+
+func (sfp StructFootprint) EnforceObjectSelection() error {
+	return fmt.Errorf("cannot set a field of the struct at %s", formatPath(sfp.Path))
+}
@@ -0,0 +1,110 @@
+package jsonpath
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SetRFCMode makes the path follow RFC 9535 where this package diverges from
+// it by default:
+//   - the constructs the RFC does not define are rejected: the operators ===,
+//     !==, =~, ^=, $=, *=, in, between and %, the pseudo fields @index and
+//     @key, key regexes like .~/re/, key globs like .user_*, index lists like
+//     [[0,1]], first(n), last(n) and nth, array and object literals, the
+//     parent selector ^ and $$
+//   - a field after .. is written without a dot, $...key is rejected, and so
+//     is an empty field name like $.a.
+//   - a comparison with an operand selecting nothing is true for ==, <= and
+//     >= if both operands select nothing and for != otherwise, instead of
+//     never matching
+//   - a comparison of values of different types is false for == and < and the
+//     like, and true for !=, instead of never matching with a warning
+//   - an existence filter selects the element having the value even if it is
+//     null or false, whatever SetTruthy is
+func (j *Jsonpath) SetRFCMode(rfc bool) {
+	j.rfc = rfc
+}
+
+// rfcOperators are the operators of the filters RFC 9535 defines
+var rfcOperators = map[string]bool{
+	"exists": true, "!": true, "&&": true, "||": true,
+	"==": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true,
+}
+
+// checkRFC reports the first segment using a construct RFC 9535 does not
+// define
+func (j *Jsonpath) checkRFC(segments []Node) error {
+	for i, segment := range segments {
+		text, _ := j.segmentText(segments, i)
+		switch {
+		case i > 0 && segments[i-1].Type() == NodeRecursive && strings.HasPrefix(text, "."):
+			return j.segmentError(fmt.Errorf("a dot after .. is not supported in RFC mode"), segments, i)
+		case segment.Type() == NodeArray && (strings.HasPrefix(text, ".first(") || strings.HasPrefix(text, ".last(")):
+			return j.segmentError(fmt.Errorf("first(n) and last(n) are not supported in RFC mode"), segments, i)
+		case segment.Type() == NodeArray && strings.HasPrefix(text, ".nth("):
+			return j.segmentError(fmt.Errorf("nth is not supported in RFC mode"), segments, i)
+		}
+		if err := checkRFCNode(segment); err != nil {
+			return j.segmentError(err, segments, i)
+		}
+	}
+	return nil
+}
+
+// checkRFCNode checks a node and the nodes nested in it like checkRFC
+func checkRFCNode(node Node) error {
+	switch node := node.(type) {
+	case *ListNode:
+		for _, n := range node.Nodes {
+			if err := checkRFCNode(n); err != nil {
+				return err
+			}
+		}
+	case *FieldNode:
+		if node.Value == "" {
+			return fmt.Errorf("an empty field name is not supported in RFC mode")
+		}
+	case *UnionNode:
+		for _, member := range node.Nodes {
+			if err := checkRFCNode(member); err != nil {
+				return err
+			}
+		}
+	case *FunctionNode:
+		for _, arg := range node.Args {
+			if err := checkRFCNode(arg); err != nil {
+				return err
+			}
+		}
+	case *FilterNode:
+		if !rfcOperators[node.Operator] {
+			return fmt.Errorf("the operator %s is not supported in RFC mode", node.Operator)
+		}
+		if err := checkRFCNode(node.Left); err != nil {
+			return err
+		}
+		return checkRFCNode(node.Right)
+	case *PseudoFieldNode, *ArithmeticNode, *KeyRegexNode, *GlobFieldNode, *RegexNode, *IndexListNode, *LiteralNode, *SecondaryRootNode, *ParentNode:
+		return fmt.Errorf("%s is not supported in RFC mode", canonical(node))
+	}
+	return nil
+}
+
+// compareRFC compares the operands of a filter like RFC 9535, an operand
+// which selects nothing is given as nil with its ok false
+func (j *Jsonpath) compareRFC(operator string, left interface{}, leftOk bool, right interface{}, rightOk bool) bool {
+	if !leftOk || !rightOk {
+		switch operator {
+		case "==", "<=", ">=":
+			return leftOk == rightOk
+		case "!=":
+			return leftOk != rightOk
+		}
+		return false
+	}
+	pass, err := j.genericCompare(operator, left, right)
+	if err != nil {
+		return operator == "!="
+	}
+	return pass
+}
@@ -0,0 +1,126 @@
+package jsonpath
+
+import "context"
+
+// Stream is like Get, but every result is sent on the returned channel as
+// soon as it is found, so that a path like $..* over a huge document never
+// holds all its results. The results channel is closed when the evaluation
+// ends, and then the error channel yields the error of the evaluation if any.
+// A consumer which may stop before draining the results must use
+// StreamContext instead. The path must not be used until the results channel
+// is closed.
+func (j *Jsonpath) Stream() (<-chan interface{}, <-chan error) {
+	return j.StreamContext(context.Background())
+}
+
+// StreamContext is like Stream, but the evaluation stops with ctx.Err() once
+// ctx is cancelled, which is how a consumer stops early.
+func (j *Jsonpath) StreamContext(ctx context.Context) (<-chan interface{}, <-chan error) {
+	results := make(chan interface{})
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(results)
+		j.ctx = ctx
+		j.writeMode = false
+		defer func() {
+			j.ctx = nil
+			j.segments = nil
+		}()
+		root, segments, err := j.root()
+		if err != nil {
+			errs <- err
+			return
+		}
+		j.segments = segments
+		emit := func(result interface{}) error {
+			select {
+			case results <- result:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if err := j.streamSegments([]Footprint{root}, segments, 0, emit); err != nil {
+			errs <- err
+		}
+	}()
+	return results, errs
+}
+
+// streamSegments evaluates the segments from i on every footprint one at a
+// time, depth first, and emits the results in the order of Get
+func (j *Jsonpath) streamSegments(footprints []Footprint, segments []Node, i int, emit func(interface{}) error) error {
+	if err := j.checkContext(); err != nil {
+		return err
+	}
+	if i == len(segments) {
+		for _, footprint := range expandFootprints(footprints, true) {
+			result := []interface{}{footprint.HolderPtr()}
+			if j.flatten != 0 {
+				result = flattenResult(result, j.flatten)
+			}
+			if j.skipNull {
+				result = skipNullResult(result)
+			}
+			if j.leavesOnly {
+				result = leavesResult(result)
+			}
+			for _, r := range result {
+				if err := emit(r); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if _, ok := segments[i].(*RecursiveNode); ok {
+		// the descendants are visited one at a time instead of being
+		// collected by evalRecursive
+		for _, footprint := range expandFootprints(footprints, false) {
+			if err := j.streamRecursive(footprint, segments, i, emit); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if _, ok := segments[i].(*UnionNode); ok {
+		// a union selects what each of its members selects of all the
+		// footprints in turn, so it is evaluated on all of them at once
+		selected, err := j.walkSegment(footprints, segments, i)
+		if err != nil {
+			return err
+		}
+		return j.streamSegments(selected, segments, i+1, emit)
+	}
+	for _, footprint := range footprints {
+		selected, err := j.walkSegment([]Footprint{footprint}, segments, i)
+		if err != nil {
+			return err
+		}
+		if err := j.streamSegments(selected, segments, i+1, emit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamRecursive evaluates the segments after the recursive descent i on the
+// footprint and then on each of its descendants, like
+// recursivelyCollectFootprint collects them
+func (j *Jsonpath) streamRecursive(footprint Footprint, segments []Node, i int, emit func(interface{}) error) error {
+	if err := j.streamSegments([]Footprint{footprint.LeaveItAsItIs()}, segments, i+1, emit); err != nil {
+		return err
+	}
+	footprint, err := footprint.SelectAll()
+	if err != nil {
+		return nil
+	}
+	children, _ := footprint.Expand()
+	for _, child := range children {
+		if err := j.streamRecursive(child, segments, i, emit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package jsonpath
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	exprs := []string{
+		`$..*`,
+		`$..`,
+		`$..price`,
+		`$..book[?(@.price < 10)].title`,
+		`$.store.book[*].author`,
+		`$..[0]`,
+		`$.store.book[-1:]`,
+		`$.store['bicycle','book'][0,*]`,
+		`$.missing`,
+	}
+	for _, expr := range exprs {
+		j, err := New(expr, expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.InitData(ConvertToJsonObj(bookstoreData))
+		result, err := j.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectation := resultValues(result)
+
+		results, errs := j.Stream()
+		streamed := make([]interface{}, 0)
+		for r := range results {
+			streamed = append(streamed, *r.(*interface{}))
+		}
+		if err := <-errs; err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		// the members of an object are in no particular order
+		if !Equal(streamed, expectation) || len(streamed) != len(expectation) {
+			t.Errorf("%s: expect the results of Get %v, got %v", expr, expectation, streamed)
+		}
+	}
+
+	// the elements of arrays are streamed in the order of Get
+	for _, expr := range []string{`$..*`, `$[1:][*]`, `$[0,1][1,0]`, `$..[?(@ > 2)]`} {
+		j, err := New(expr, expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.InitData(ConvertToJsonObj(`[[1, 2, [3, 4]], [5, [6]], [7, 8]]`))
+		result, err := j.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectation := resultValues(result)
+		results, errs := j.Stream()
+		streamed := make([]interface{}, 0)
+		for r := range results {
+			streamed = append(streamed, *r.(*interface{}))
+		}
+		if err := <-errs; err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		if !reflect.DeepEqual(streamed, expectation) {
+			t.Errorf("%s: expect the results of Get in order %v, got %v", expr, expectation, streamed)
+		}
+	}
+}
+
+func TestStreamEarlyClose(t *testing.T) {
+	j, err := New("early", `$..*`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.InitData(ConvertToJsonObj(bookstoreData))
+	ctx, cancel := context.WithCancel(context.Background())
+	results, errs := j.StreamContext(ctx)
+	for i := 0; i < 2; i++ {
+		if _, ok := <-results; !ok {
+			t.Fatal("expect more results")
+		}
+	}
+	cancel()
+	for range results {
+	}
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("expect the evaluation to be cancelled, got %v", err)
+	}
+}
+
+func TestStreamError(t *testing.T) {
+	j, err := New("strict", `$.store.book[10]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.SetStrict(true)
+	j.InitData(ConvertToJsonObj(bookstoreData))
+	results, errs := j.Stream()
+	for r := range results {
+		t.Errorf("expect no results, got %v", r)
+	}
+	if err := <-errs; err == nil {
+		t.Errorf("expect an error of an index out of range")
+	}
+}
@@ -5,6 +5,7 @@ package template
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -299,8 +300,25 @@ func basicKind(v reflect.Value) (kind, error) {
 	return invalidKind, errBadComparisonType
 }
 
+// number converts a json.Number to int64, or to float64 if it is not an
+// integer, so that it is compared as a number rather than a string.
+func number(arg interface{}) interface{} {
+	n, ok := arg.(json.Number)
+	if !ok {
+		return arg
+	}
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	if f, err := n.Float64(); err == nil {
+		return f
+	}
+	return arg
+}
+
 // eq evaluates the comparison a == b || a == c || ...
 func eq(arg1 interface{}, arg2 ...interface{}) (bool, error) {
+	arg1 = number(arg1)
 	v1 := reflect.ValueOf(arg1)
 	k1, err := basicKind(v1)
 	if err != nil {
@@ -310,7 +328,7 @@ func eq(arg1 interface{}, arg2 ...interface{}) (bool, error) {
 		return false, errNoComparison
 	}
 	for _, arg := range arg2 {
-		v2 := reflect.ValueOf(arg)
+		v2 := reflect.ValueOf(number(arg))
 		k2, err := basicKind(v2)
 		if err != nil {
 			return false, err
@@ -364,6 +382,7 @@ func ne(arg1, arg2 interface{}) (bool, error) {
 
 // lt evaluates the comparison a < b.
 func lt(arg1, arg2 interface{}) (bool, error) {
+	arg1, arg2 = number(arg1), number(arg2)
 	v1 := reflect.ValueOf(arg1)
 	k1, err := basicKind(v1)
 	if err != nil {
@@ -383,9 +402,9 @@ func lt(arg1, arg2 interface{}) (bool, error) {
 		case k1 == uintKind && k2 == intKind:
 			truth = v2.Int() >= 0 && v1.Uint() < uint64(v2.Int())
 		case k1 == intKind && k2 == floatKind:
-			truth = float64(v1.Int()) <= v2.Float()
+			truth = float64(v1.Int()) < v2.Float()
 		case k1 == floatKind && k2 == intKind:
-			truth = v1.Float() <= float64(v2.Int())
+			truth = v1.Float() < float64(v2.Int())
 		default:
 			return false, errBadComparison
 		}
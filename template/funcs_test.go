@@ -0,0 +1,28 @@
+package template
+
+import "testing"
+
+func TestLtEqualNumbers(t *testing.T) {
+	cases := []struct {
+		arg1, arg2  interface{}
+		expectation bool
+	}{
+		{1, 1.0, false},
+		{1.0, 1, false},
+		{1, 1.5, true},
+		{1.5, 1, false},
+		{-2.5, -2, true},
+	}
+	for _, c := range cases {
+		truth, err := lt(c.arg1, c.arg2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if truth != c.expectation {
+			t.Errorf("lt(%v, %v): expect %v, got %v", c.arg1, c.arg2, c.expectation, truth)
+		}
+	}
+	if truth, err := ge(1, 1.0); err != nil || !truth {
+		t.Errorf("ge(1, 1.0): expect true, got %v, %v", truth, err)
+	}
+}
@@ -0,0 +1,91 @@
+// Package yaml evaluates jsonpath expressions over yaml documents. It is kept
+// apart from the jsonpath package so that only its users depend on the yaml
+// decoder.
+package yaml
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/zucong/jsonpath"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// New is like jsonpath.New, and it decodes yamlBytes as the data to evaluate.
+// The yaml is converted to the generic json model: the keys of a mapping
+// become strings, like 1 to "1" and true to "true", numbers become float64
+// and timestamps become RFC 3339 strings. A mapping whose keys clash after
+// the conversion or which has a key of a mapping or a sequence is an error.
+func New(name string, expr string, yamlBytes []byte) (*jsonpath.Jsonpath, error) {
+	j, err := jsonpath.New(name, expr)
+	if err != nil {
+		return nil, err
+	}
+	var obj interface{}
+	if err := yamlv3.Unmarshal(yamlBytes, &obj); err != nil {
+		return nil, fmt.Errorf("cannot decode yaml: %w", err)
+	}
+	if obj, err = fromYAML(obj, nil); err != nil {
+		return nil, fmt.Errorf("cannot decode yaml: %w", err)
+	}
+	j.InitData(obj)
+	return j, nil
+}
+
+// fromYAML converts a value decoded by yaml to the generic json model, path
+// is the segments of where the value is, for the errors, it is reused as a
+// stack since the conversion stops at the first error
+func fromYAML(value interface{}, path []interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(value))
+		for key, v := range value {
+			converted, err := fromYAML(v, append(path, key))
+			if err != nil {
+				return nil, err
+			}
+			obj[key] = converted
+		}
+		return obj, nil
+	case map[interface{}]interface{}:
+		obj := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			key, err := jsonpath.StringKey(k)
+			if err != nil {
+				return nil, fmt.Errorf("%v at %s", err, jsonpath.BuildPath(path...))
+			}
+			if _, ok := obj[key]; ok {
+				return nil, fmt.Errorf("the key %s is duplicated as a string at %s", key, jsonpath.BuildPath(path...))
+			}
+			if obj[key], err = fromYAML(v, append(path, key)); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	case []interface{}:
+		arr := make([]interface{}, len(value))
+		for i, v := range value {
+			converted, err := fromYAML(v, append(path, i))
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = converted
+		}
+		return arr, nil
+	case int:
+		return float64(value), nil
+	case int64:
+		return float64(value), nil
+	case uint64:
+		return float64(value), nil
+	case float64:
+		if math.IsInf(value, 0) || math.IsNaN(value) {
+			return nil, fmt.Errorf("the number %v is not valid json at %s", value, jsonpath.BuildPath(path...))
+		}
+		return value, nil
+	case time.Time:
+		return value.Format(time.RFC3339Nano), nil
+	}
+	return value, nil
+}
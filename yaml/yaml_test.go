@@ -0,0 +1,55 @@
+package yaml
+
+import (
+	"testing"
+
+	"github.com/zucong/jsonpath"
+)
+
+func TestNew(t *testing.T) {
+	source := []byte(`
+store:
+  book:
+    - title: Sayings of the Century
+      price: 8.95
+    - title: Moby Dick
+      price: 8
+  ports:
+    80: http
+    443: https
+  flags:
+    true: on
+    false: off
+`)
+	cases := []struct {
+		expr        string
+		expectation string
+	}{
+		{`$.store.book[?(@.price < 9)].title`, `["Sayings of the Century", "Moby Dick"]`},
+		{`$.store.book[1].price`, `[8]`},
+		{`$.store.ports['443']`, `["https"]`},
+		{`$.store.flags.true`, `["on"]`},
+	}
+	for _, c := range cases {
+		j, err := New("yaml", c.expr, source)
+		if err != nil {
+			t.Fatalf("%s: %v", c.expr, err)
+		}
+		result, err := j.Get()
+		if err != nil {
+			t.Fatalf("%s: %v", c.expr, err)
+		}
+		values := make([]interface{}, len(result))
+		for i, r := range result {
+			values[i] = *r.(*interface{})
+		}
+		if !jsonpath.Equal(values, jsonpath.ConvertToJsonObj(c.expectation)) {
+			t.Errorf("%s: expect %s, got %v", c.expr, c.expectation, values)
+		}
+	}
+	for _, source := range []string{"a: [1", "1.0: a\n\"1\": b\n", "? [a, b]\n: c\n"} {
+		if _, err := New("yaml", `$`, []byte(source)); err == nil {
+			t.Errorf("%q: expect an error", source)
+		}
+	}
+}